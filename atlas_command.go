@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/layout"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+type AtlasCommand struct {
+	Families []string `short:"t" long:"family" description:"Family to show, repeat it to compare families side by side" required:"true"`
+	IDs      string   `long:"ids" description:"IDs to show of each family, as a range expression, all of them by default"`
+	Module   int      `short:"m" long:"module" description:"Size of a module in pixels" default:"4"`
+	Columns  int      `long:"columns" description:"Number of tags per row of each family" default:"16"`
+	Rows     int      `long:"rows" description:"Number of rows of each family per page" default:"16"`
+	DPI      int      `short:"d" long:"dpi" description:"DPI of the outputs" default:"96"`
+	File     string   `short:"f" long:"file" description:"Output pages, where a verb such as %02d is replaced by the page number starting at 1" default:"atlas_%02d.png"`
+}
+
+// blocks returns the IDs selected of each family.
+func (c *AtlasCommand) blocks() ([]layout.FamilyBlock, error) {
+	var expr *ranges.RangeExpression
+	if len(strings.TrimSpace(c.IDs)) > 0 {
+		var err error
+		expr, err = ranges.ParseRangeExpression(c.IDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := []layout.FamilyBlock{}
+	for _, name := range c.Families {
+		tf, err := families.GetFamily(name)
+		if err != nil {
+			return nil, err
+		}
+		block := layout.FamilyBlock{
+			Family: tf,
+			Ranges: []ranges.Range{{Begin: 0, End: len(tf.Codes)}},
+		}
+		if expr != nil {
+			ids, err := expr.Select(tf)
+			if err != nil {
+				return nil, err
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("No tag of %s is selected by '%s'", name, c.IDs)
+			}
+			block.Ranges = ranges.RangesOfIDs(ids)
+		}
+		res = append(res, block)
+	}
+	return res, nil
+}
+
+func (c *AtlasCommand) Execute(args []string) error {
+	blocks, err := c.blocks()
+	if err != nil {
+		return err
+	}
+	atlas := &layout.AtlasLayouter{
+		Module:  c.Module,
+		Columns: c.Columns,
+		Rows:    c.Rows,
+	}
+	width, height, err := atlas.Size(blocks)
+	if err != nil {
+		return err
+	}
+	pages := atlas.Pages(blocks)
+	verbs, err := pathVerbs(c.File)
+	if err != nil {
+		return err
+	}
+	if pages > 1 && verbs == 0 {
+		return fmt.Errorf("Output '%s' needs a verb such as %%02d for the numbers of %d pages", c.File, pages)
+	}
+	for page := 0; page < pages; page++ {
+		drawer, err := newFileDrawer(numberedPath(c.File, page+1), dotsToMM(width, c.DPI), dotsToMM(height, c.DPI), c.DPI, false)
+		if err != nil {
+			return err
+		}
+		if err := atlas.LayoutPage(drawer, blocks, page); err != nil {
+			drawer.Close()
+			return err
+		}
+		if err := drawer.Close(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Atlas of %d pages of %dx%d pixels written", pages, width, height)
+	return nil
+}
@@ -0,0 +1,416 @@
+package drawing
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"path/filepath"
+)
+
+// bandEncoder writes an image band by band, from top to bottom. All
+// bands but the last must have the same height. Compress may be called
+// concurrently, its results must be written in order.
+type bandEncoder interface {
+	Compress(r raster) ([]byte, error)
+	Write(rows int, compressed []byte) error
+	Close() error
+}
+
+type bandEncoderFactory func(w io.WriteSeeker, width, height int, DPI float64, mode RasterMode) (bandEncoder, error)
+
+var encoders = map[string]bandEncoderFactory{
+	".png":  newPNGEncoder,
+	".tiff": newTIFFEncoder,
+	".tif":  newTIFFEncoder,
+}
+
+func matchEncoder(filename string) (bandEncoderFactory, error) {
+	ext := filepath.Ext(filename)
+	if res, ok := encoders[ext]; ok == true {
+		return res, nil
+	}
+	return nil, fmt.Errorf("Unsupported file extension '%s'", ext)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunkWriter writes each buffer it receives as a chunk.
+type pngChunkWriter struct {
+	w    io.Writer
+	name string
+}
+
+func (c *pngChunkWriter) Write(data []byte) (int, error) {
+	if _, err := c.w.Write(pngChunk(c.name, data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// pngEncoder writes 8 bit grayscale, RGB or RGBA rows with the sub filter,
+// or 1 bit rows unfiltered, in IDAT chunks of up to 64kB. Each band is
+// deflated on its own, ending on a sync flush, so the bands are
+// compressed concurrently and concatenated in a single zlib stream.
+type pngEncoder struct {
+	w      io.Writer
+	mode   RasterMode
+	stride int
+	// bpp is the number of bytes per pixel for the sub filter
+	bpp  int
+	idat *bufio.Writer
+	// checksum is the adler32 of the rows written so far
+	checksum uint32
+}
+
+const (
+	pngGrayscale = 0
+	pngRGB       = 2
+	pngRGBA      = 6
+)
+
+func newPNGEncoder(w io.WriteSeeker, width, height int, DPI float64, mode RasterMode) (bandEncoder, error) {
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], uint32(width))
+	binary.BigEndian.PutUint32(header[4:], uint32(height))
+	// default compression, filter and no interlace
+	header[8], header[9] = 8, pngGrayscale
+	stride, bpp := width, 1
+	switch mode {
+	case BilevelMode:
+		header[8] = 1
+		stride = (width + 7) / 8
+	case RGBMode:
+		header[9] = pngRGB
+		stride, bpp = 3*width, 3
+	case RGBAMode:
+		header[9] = pngRGBA
+		stride, bpp = 4*width, 4
+	}
+	for _, chunk := range [][]byte{pngSignature, pngChunk("IHDR", header), pngPhysChunk(DPI)} {
+		if _, err := w.Write(chunk); err != nil {
+			return nil, err
+		}
+	}
+	res := &pngEncoder{
+		w:      w,
+		mode:   mode,
+		stride: stride,
+		bpp:    bpp,
+		idat:   bufio.NewWriterSize(&pngChunkWriter{w: w, name: "IDAT"}, 1<<16),
+		// the checksum of no data
+		checksum: 1,
+	}
+	// zlib header for deflate with the default compression level
+	if _, err := res.idat.Write([]byte{0x78, 0x9c}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pngBandTrailerSize is the size of the adler32 checksum and length of
+// the filtered rows, which follow the deflated rows of a band.
+const pngBandTrailerSize = 8
+
+// Compress returns the deflated filtered rows of r, followed by their
+// checksum and length.
+func (e *pngEncoder) Compress(r raster) ([]byte, error) {
+	rows := e.filter(r)
+	buffer := bytes.NewBuffer(nil)
+	fw, err := flate.NewWriter(buffer, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(rows); err != nil {
+		return nil, err
+	}
+	// the sync flush ends the band on a byte boundary, without marking
+	// the last block, so bands can be concatenated
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	trailer := make([]byte, pngBandTrailerSize)
+	binary.BigEndian.PutUint32(trailer[0:], adler32.Checksum(rows))
+	binary.BigEndian.PutUint32(trailer[4:], uint32(len(rows)))
+	buffer.Write(trailer)
+	return buffer.Bytes(), nil
+}
+
+// filter returns the rows of r, each preceded by its filter type.
+func (e *pngEncoder) filter(r raster) []byte {
+	bounds := r.Bounds()
+	res := make([]byte, 0, bounds.Dy()*(1+e.stride))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		pixels := r.Row(y)
+		if e.mode == BilevelMode {
+			// PNG grayscale has 0 for black
+			res = append(res, 0)
+			for _, v := range pixels {
+				res = append(res, ^v)
+			}
+			continue
+		}
+		res = append(res, 1)
+		res = append(res, pixels[:e.bpp]...)
+		for i := e.bpp; i < len(pixels); i++ {
+			res = append(res, pixels[i]-pixels[i-e.bpp])
+		}
+	}
+	return res
+}
+
+func (e *pngEncoder) Write(rows int, compressed []byte) error {
+	n := len(compressed) - pngBandTrailerSize
+	trailer := compressed[n:]
+	e.checksum = adler32Combine(e.checksum, binary.BigEndian.Uint32(trailer[0:]), binary.BigEndian.Uint32(trailer[4:]))
+	_, err := e.idat.Write(compressed[:n])
+	return err
+}
+
+// adler32Combine returns the adler32 checksum of the concatenation of
+// two buffers, from their checksums and the length of the second one.
+func adler32Combine(adler1, adler2, len2 uint32) uint32 {
+	const base = 65521
+	rem := len2 % base
+	sum1 := adler1 & 0xffff
+	sum2 := rem * sum1 % base
+	sum1 += (adler2 & 0xffff) + base - 1
+	sum2 += (adler1 >> 16) + (adler2 >> 16) + base - rem
+	if sum1 >= base {
+		sum1 -= base
+	}
+	if sum1 >= base {
+		sum1 -= base
+	}
+	if sum2 >= 2*base {
+		sum2 -= 2 * base
+	}
+	if sum2 >= base {
+		sum2 -= base
+	}
+	return sum1 | sum2<<16
+}
+
+func (e *pngEncoder) Close() error {
+	// an empty last block ends the deflate stream
+	last := bytes.NewBuffer(nil)
+	fw, err := flate.NewWriter(last, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	binary.Write(last, binary.BigEndian, e.checksum)
+	if _, err := last.WriteTo(e.idat); err != nil {
+		return err
+	}
+	if err := e.idat.Flush(); err != nil {
+		return err
+	}
+	_, err = e.w.Write(pngChunk("IEND", nil))
+	return err
+}
+
+const (
+	tiffShort             = 3
+	tiffLong              = 4
+	tiffCompressionG4     = 4
+	tiffCompressionFlate  = 8
+	tiffWhiteIsZero       = 0
+	tiffBlackIsZero       = 1
+	tiffRGB               = 2
+	tiffResolutionInch    = 2
+	tiffHorizontalDiff    = 2
+	tiffUnassociatedAlpha = 2
+	tiffHeaderSize        = 8
+)
+
+type tiffEntry struct {
+	Tag, Type uint16
+	Count     uint32
+	Value     uint32
+}
+
+// tiffEncoder writes each band as a strip, compressed with CCITT Group
+// 4 for bilevel images, or deflate with horizontal differencing. The
+// IFD is written last, and its offset patched in the header.
+type tiffEncoder struct {
+	w             io.WriteSeeker
+	width, height int
+	DPI           float64
+	mode          RasterMode
+
+	offset          uint32
+	rowsPerStrip    int
+	stripOffsets    []uint32
+	stripByteCounts []uint32
+}
+
+var tiffOrder = binary.LittleEndian
+
+func newTIFFEncoder(w io.WriteSeeker, width, height int, DPI float64, mode RasterMode) (bandEncoder, error) {
+	header := make([]byte, tiffHeaderSize)
+	copy(header, "II")
+	tiffOrder.PutUint16(header[2:], 42)
+	// the IFD offset is written on Close
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &tiffEncoder{
+		w:      w,
+		width:  width,
+		height: height,
+		DPI:    DPI,
+		mode:   mode,
+		offset: tiffHeaderSize,
+	}, nil
+}
+
+func (e *tiffEncoder) Compress(r raster) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if e.mode == BilevelMode {
+		err := encodeG4(buffer, r.(*Bilevel))
+		return buffer.Bytes(), err
+	}
+	samples := e.samplesPerPixel()
+	zw := zlib.NewWriter(buffer)
+	row := make([]byte, samples*e.width)
+	for y := r.Bounds().Min.Y; y < r.Bounds().Max.Y; y++ {
+		pixels := r.Row(y)
+		copy(row, pixels[:samples])
+		for i := samples; i < len(pixels); i++ {
+			row[i] = pixels[i] - pixels[i-samples]
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	err := zw.Close()
+	return buffer.Bytes(), err
+}
+
+func (e *tiffEncoder) samplesPerPixel() int {
+	switch e.mode {
+	case RGBMode:
+		return 3
+	case RGBAMode:
+		return 4
+	}
+	return 1
+}
+
+func (e *tiffEncoder) Write(rows int, compressed []byte) error {
+	if e.rowsPerStrip == 0 {
+		e.rowsPerStrip = rows
+	}
+	if _, err := e.w.Write(compressed); err != nil {
+		return err
+	}
+	e.stripOffsets = append(e.stripOffsets, e.offset)
+	e.stripByteCounts = append(e.stripByteCounts, uint32(len(compressed)))
+	e.offset += uint32(len(compressed))
+	return nil
+}
+
+func (e *tiffEncoder) Close() error {
+	buffer := bytes.NewBuffer(nil)
+	// the IFD starts on a word boundary
+	if e.offset%2 != 0 {
+		buffer.WriteByte(0)
+		e.offset++
+	}
+	ifdOffset := e.offset
+
+	samples := uint32(e.samplesPerPixel())
+	bitsPerSample, compression, photometric := 8, tiffCompressionFlate, tiffBlackIsZero
+	switch e.mode {
+	case BilevelMode:
+		bitsPerSample, compression, photometric = 1, tiffCompressionG4, tiffWhiteIsZero
+	case RGBMode, RGBAMode:
+		photometric = tiffRGB
+	}
+	nStrips := uint32(len(e.stripOffsets))
+	entries := []tiffEntry{
+		{256, tiffLong, 1, uint32(e.width)},
+		{257, tiffLong, 1, uint32(e.height)},
+		{258, tiffShort, samples, uint32(bitsPerSample)},
+		{259, tiffShort, 1, uint32(compression)},
+		{262, tiffShort, 1, uint32(photometric)},
+		{273, tiffLong, nStrips, 0},
+		{277, tiffShort, 1, samples},
+		{278, tiffLong, 1, uint32(e.rowsPerStrip)},
+		{279, tiffLong, nStrips, 0},
+		{tiffXResolution, tiffRational, 1, 0},
+		{tiffYResolution, tiffRational, 1, 0},
+		{296, tiffShort, 1, tiffResolutionInch},
+	}
+	if e.mode != BilevelMode {
+		entries = append(entries, tiffEntry{317, tiffShort, 1, tiffHorizontalDiff})
+	}
+	if e.mode == RGBAMode {
+		entries = append(entries, tiffEntry{338, tiffShort, 1, tiffUnassociatedAlpha})
+	}
+
+	// the values that do not fit in an entry follow the IFD
+	extra := bytes.NewBuffer(nil)
+	extraOffset := ifdOffset + 2 + 12*uint32(len(entries)) + 4
+	appendExtra := func(values interface{}) uint32 {
+		offset := extraOffset + uint32(extra.Len())
+		binary.Write(extra, tiffOrder, values)
+		// values start on a word boundary
+		if extra.Len()%2 != 0 {
+			extra.WriteByte(0)
+		}
+		return offset
+	}
+	entries[9].Value = appendExtra(tiffResolution(e.DPI))
+	entries[10].Value = entries[9].Value
+	if samples > 1 {
+		perSample := make([]uint16, samples)
+		for i := range perSample {
+			perSample[i] = uint16(bitsPerSample)
+		}
+		entries[2].Value = appendExtra(perSample)
+	}
+	if nStrips == 1 {
+		entries[5].Value = e.stripOffsets[0]
+		entries[8].Value = e.stripByteCounts[0]
+	} else {
+		entries[5].Value = appendExtra(e.stripOffsets)
+		entries[8].Value = appendExtra(e.stripByteCounts)
+	}
+
+	binary.Write(buffer, tiffOrder, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buffer, tiffOrder, entry.Tag)
+		binary.Write(buffer, tiffOrder, entry.Type)
+		binary.Write(buffer, tiffOrder, entry.Count)
+		if entry.Type == tiffShort && entry.Count == 1 {
+			// a short value is left justified in the 4 bytes
+			binary.Write(buffer, tiffOrder, uint16(entry.Value))
+			binary.Write(buffer, tiffOrder, uint16(0))
+		} else {
+			binary.Write(buffer, tiffOrder, entry.Value)
+		}
+	}
+	// no next IFD
+	binary.Write(buffer, tiffOrder, uint32(0))
+	extra.WriteTo(buffer)
+	if _, err := buffer.WriteTo(e.w); err != nil {
+		return err
+	}
+
+	if _, err := e.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	if err := binary.Write(e.w, tiffOrder, ifdOffset); err != nil {
+		return err
+	}
+	_, err := e.w.Seek(0, io.SeekEnd)
+	return err
+}
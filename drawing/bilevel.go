@@ -0,0 +1,109 @@
+package drawing
+
+import (
+	"image"
+	"image/color"
+)
+
+// Bilevel is an image with 1 bit per pixel, a set bit being black.
+// Rows start on a byte boundary, the first pixel in the most
+// significant bit.
+type Bilevel struct {
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewBilevel returns a white Bilevel image.
+func NewBilevel(r image.Rectangle) *Bilevel {
+	stride := (r.Dx() + 7) / 8
+	return &Bilevel{
+		Pix:    make([]byte, stride*r.Dy()),
+		Stride: stride,
+		Rect:   r,
+	}
+}
+
+func isBlack(c color.Color) bool {
+	return color.GrayModel.Convert(c).(color.Gray).Y < 0x80
+}
+
+func (b *Bilevel) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+func (b *Bilevel) Bounds() image.Rectangle {
+	return b.Rect
+}
+
+func (b *Bilevel) offset(x, y int) (int, byte) {
+	x -= b.Rect.Min.X
+	return (y-b.Rect.Min.Y)*b.Stride + x/8, 0x80 >> uint(x%8)
+}
+
+func (b *Bilevel) BlackAt(x, y int) bool {
+	if (image.Point{x, y}).In(b.Rect) == false {
+		return false
+	}
+	i, mask := b.offset(x, y)
+	return b.Pix[i]&mask != 0
+}
+
+func (b *Bilevel) At(x, y int) color.Color {
+	if b.BlackAt(x, y) == true {
+		return color.Black
+	}
+	return color.White
+}
+
+func (b *Bilevel) SetBlack(x, y int, black bool) {
+	if (image.Point{x, y}).In(b.Rect) == false {
+		return
+	}
+	i, mask := b.offset(x, y)
+	if black == true {
+		b.Pix[i] |= mask
+	} else {
+		b.Pix[i] &^= mask
+	}
+}
+
+func (b *Bilevel) Set(x, y int, c color.Color) {
+	b.SetBlack(x, y, isBlack(c))
+}
+
+// Row returns the bytes of row y.
+func (b *Bilevel) Row(y int) []byte {
+	start := (y - b.Rect.Min.Y) * b.Stride
+	return b.Pix[start : start+b.Stride]
+}
+
+// FillRect sets all the pixels of r to c.
+func (b *Bilevel) FillRect(r image.Rectangle, c color.Color) {
+	r = r.Intersect(b.Rect)
+	if r.Empty() {
+		return
+	}
+	var value byte = 0x00
+	if isBlack(c) == true {
+		value = 0xff
+	}
+	x0 := r.Min.X - b.Rect.Min.X
+	x1 := r.Max.X - b.Rect.Min.X
+	firstByte, lastByte := x0/8, (x1-1)/8
+	firstMask := byte(0xff >> uint(x0%8))
+	lastMask := byte(0xff << uint(7-(x1-1)%8))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := b.Row(y)
+		if firstByte == lastByte {
+			mask := firstMask & lastMask
+			row[firstByte] = row[firstByte]&^mask | value&mask
+			continue
+		}
+		row[firstByte] = row[firstByte]&^firstMask | value&firstMask
+		for i := firstByte + 1; i < lastByte; i++ {
+			row[i] = value
+		}
+		row[lastByte] = row[lastByte]&^lastMask | value&lastMask
+	}
+}
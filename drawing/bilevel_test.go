@@ -0,0 +1,87 @@
+package drawing
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+
+	"golang.org/x/image/tiff"
+	. "gopkg.in/check.v1"
+)
+
+type BilevelSuite struct {
+	Images []*Bilevel
+}
+
+var _ = Suite(&BilevelSuite{})
+
+func (s *BilevelSuite) SetUpSuite(c *C) {
+	r := rand.New(rand.NewSource(42))
+
+	noise := NewBilevel(image.Rect(0, 0, 61, 37))
+	r.Read(noise.Pix)
+
+	// long runs need the makeup codes, up to the extended ones
+	runs := NewBilevel(image.Rect(0, 0, 6000, 9))
+	runs.FillRect(image.Rect(100, 0, 2900, 3), color.Black)
+	runs.FillRect(image.Rect(0, 3, 5990, 6), color.Black)
+	runs.FillRect(image.Rect(70, 6, 5999, 9), color.Black)
+
+	// a few tags with their borders, offset from the byte boundaries
+	tags := NewBilevel(image.Rect(0, 0, 203, 101))
+	for i := 0; i < 6; i++ {
+		x := 3 + i*33
+		tags.FillRect(image.Rect(x, 5, x+30, 35), color.Black)
+		for j := 0; j < 36; j++ {
+			if r.Intn(2) == 0 {
+				tags.FillRect(image.Rect(x+3+(j%6)*4, 8+(j/6)*4, x+7+(j%6)*4, 12+(j/6)*4), color.White)
+			}
+		}
+	}
+
+	s.Images = []*Bilevel{noise, runs, tags}
+}
+
+func checkSameImage(c *C, decoded image.Image, expected *Bilevel) {
+	c.Assert(decoded.Bounds(), Equals, expected.Bounds())
+	for y := expected.Rect.Min.Y; y < expected.Rect.Max.Y; y++ {
+		for x := expected.Rect.Min.X; x < expected.Rect.Max.X; x++ {
+			black := isBlack(decoded.At(x, y))
+			if black != expected.BlackAt(x, y) {
+				c.Fatalf("Pixel (%d,%d) differs: expected black %t", x, y, expected.BlackAt(x, y))
+			}
+		}
+	}
+}
+
+func (s *BilevelSuite) TestFillRect(c *C) {
+	b := NewBilevel(image.Rect(0, 0, 20, 2))
+	b.FillRect(image.Rect(3, 0, 5, 1), color.Black)
+	b.FillRect(image.Rect(6, 1, 30, 2), color.Black)
+	b.FillRect(image.Rect(10, 1, 12, 2), color.White)
+	c.Check(b.Pix, DeepEquals, []byte{0x18, 0x00, 0x00, 0x03, 0xcf, 0xf0})
+	c.Check(b.At(3, 0), Equals, color.Black)
+	c.Check(b.At(5, 0), Equals, color.White)
+}
+
+func (s *BilevelSuite) TestG4TIFF(c *C) {
+	for _, b := range s.Images {
+		for _, bandHeight := range []int{b.Rect.Dy(), 4} {
+			data := encodeBands(c, ".tiff", b, 2400, BilevelMode, bandHeight)
+			decoded, err := tiff.Decode(bytes.NewReader(data))
+			c.Assert(err, IsNil)
+			checkSameImage(c, decoded, b)
+		}
+	}
+}
+
+func (s *BilevelSuite) TestPNG(c *C) {
+	for _, b := range s.Images {
+		data := encodeBands(c, ".png", b, 2400, BilevelMode, 4)
+		decoded, err := png.Decode(bytes.NewReader(data))
+		c.Assert(err, IsNil)
+		checkSameImage(c, decoded, b)
+	}
+}
@@ -0,0 +1,188 @@
+package drawing
+
+import (
+	"io"
+	"math/bits"
+)
+
+// CCITT T.4 codes, as strings of bits. Terminating codes are indexed
+// by run length, makeup codes by run length / 64 - 1.
+var (
+	whiteTerminatingCodes = []string{
+		"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+		"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+		"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+		"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+		"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+		"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+		"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+		"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+	}
+	whiteMakeupCodes = []string{
+		"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+		"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+		"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+		"010011010", "011000", "010011011",
+	}
+	blackTerminatingCodes = []string{
+		"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+		"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+		"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+		"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+		"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+		"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+		"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+		"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+	}
+	blackMakeupCodes = []string{
+		"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+		"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+		"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+		"0000001011011", "0000001100100", "0000001100101",
+	}
+	// extended makeup codes for runs of 1792 to 2560, shared by both
+	// colors.
+	extendedMakeupCodes = []string{
+		"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101", "000000010110",
+		"000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+	}
+)
+
+const (
+	g4Pass       = "0001"
+	g4Horizontal = "001"
+	g4EOL        = "000000000001"
+	maxMakeupRun = 2560
+)
+
+// g4Vertical are the vertical mode codes, indexed by a1 - b1 + 3.
+var g4Vertical = []string{"0000010", "000010", "010", "1", "011", "000011", "0000011"}
+
+type bitWriter struct {
+	w     io.Writer
+	buf   []byte
+	acc   uint64
+	nBits uint
+	err   error
+}
+
+func (b *bitWriter) writeCode(code string) {
+	for _, c := range code {
+		b.acc <<= 1
+		if c == '1' {
+			b.acc |= 1
+		}
+		b.nBits++
+	}
+	for b.nBits >= 8 {
+		b.nBits -= 8
+		b.buf = append(b.buf, byte(b.acc>>b.nBits))
+	}
+	if len(b.buf) >= 4096 {
+		b.flush()
+	}
+}
+
+func (b *bitWriter) flush() {
+	if b.err == nil {
+		_, b.err = b.w.Write(b.buf)
+	}
+	b.buf = b.buf[:0]
+}
+
+// close pads the last byte with zeros and flushes.
+func (b *bitWriter) close() error {
+	if b.nBits > 0 {
+		b.buf = append(b.buf, byte(b.acc<<(8-b.nBits)))
+		b.nBits = 0
+	}
+	b.flush()
+	return b.err
+}
+
+func (b *bitWriter) writeRun(run int, black bool) {
+	terminating, makeup := whiteTerminatingCodes, whiteMakeupCodes
+	if black == true {
+		terminating, makeup = blackTerminatingCodes, blackMakeupCodes
+	}
+	for run >= maxMakeupRun {
+		b.writeCode(extendedMakeupCodes[len(extendedMakeupCodes)-1])
+		run -= maxMakeupRun
+	}
+	if run >= 1792 {
+		b.writeCode(extendedMakeupCodes[(run-1792)/64])
+	} else if run >= 64 {
+		b.writeCode(makeup[run/64-1])
+	}
+	b.writeCode(terminating[run%64])
+}
+
+func bitAt(row []byte, x int) bool {
+	return row[x/8]&(0x80>>uint(x%8)) != 0
+}
+
+// nextChange returns the position of the first changing element of
+// row after x, or width if there is none. The pixel before the row is
+// white.
+func nextChange(row []byte, x, width int) int {
+	if x >= width {
+		return width
+	}
+	// bits of the same color as x are 0 once xored with fill
+	var fill byte = 0x00
+	if x >= 0 && bitAt(row, x) == true {
+		fill = 0xff
+	}
+	start := x + 1
+	for i := start / 8; i < len(row); i++ {
+		diff := row[i] ^ fill
+		if i == start/8 {
+			diff &= 0xff >> uint(start%8)
+		}
+		if diff != 0 {
+			// the padding at the end of the row may differ
+			return min(8*i+bits.LeadingZeros8(diff), width)
+		}
+	}
+	return width
+}
+
+// encodeG4 writes b compressed with CCITT Group 4 (T.6), MSB first,
+// black being the 1 bit.
+func encodeG4(w io.Writer, b *Bilevel) error {
+	width := b.Rect.Dx()
+	bw := &bitWriter{w: w, buf: make([]byte, 0, 4096+8)}
+	reference := make([]byte, b.Stride)
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		coding := b.Row(y)
+		a0, black := -1, false
+		for a0 < width {
+			a1 := nextChange(coding, a0, width)
+			b1 := nextChange(reference, a0, width)
+			if b1 < width && bitAt(reference, b1) == black {
+				b1 = nextChange(reference, b1, width)
+			}
+			b2 := nextChange(reference, b1, width)
+
+			if b2 < a1 {
+				bw.writeCode(g4Pass)
+				a0 = b2
+				continue
+			}
+			if d := a1 - b1; d >= -3 && d <= 3 {
+				bw.writeCode(g4Vertical[d+3])
+				a0, black = a1, !black
+				continue
+			}
+			a2 := nextChange(coding, a1, width)
+			bw.writeCode(g4Horizontal)
+			bw.writeRun(a1-max(a0, 0), black)
+			bw.writeRun(a2-a1, !black)
+			a0 = a2
+		}
+		reference = coding
+	}
+	bw.writeCode(g4EOL)
+	bw.writeCode(g4EOL)
+	return bw.close()
+}
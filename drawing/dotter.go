@@ -1,4 +1,4 @@
-package main
+package drawing
 
 type Dotter struct {
 	dpi float64
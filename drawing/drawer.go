@@ -0,0 +1,55 @@
+// Package drawing draws tags and shapes in dots to raster, vector and
+// engraving outputs.
+package drawing
+
+import "image/color"
+
+// Drawer draws on a page in dots from its top left corner, with the
+// outputs written on Close.
+type Drawer interface {
+	DrawRectangle(x, y, w, h int, c color.Color)
+	// RotateTranslate moves the origin to (x,y) and rotates the drawing
+	// by r degrees until the matching EndRotateTranslate. Raster and
+	// engraving outputs only translate, a rotation makes Close fail.
+	RotateTranslate(x, y int, r float64)
+	EndRotateTranslate()
+	DrawLine(x1, y1, x2, y2, b int, c color.Color)
+	Label(x, y int, height int, label string, c color.Color) float64
+	DrawCircle(x, y, r, b int, c color.Color)
+	// BeginLayer draws on layer until the matching EndLayer
+	BeginLayer(l Layer)
+	EndLayer()
+	// BeginGroup groups the drawing until the matching EndGroup, with
+	// a label and metadata describing it
+	BeginGroup(label string, metadata map[string]string)
+	EndGroup()
+	Close() error
+	ToDot(float64) int
+	ToMM(int) float64
+}
+
+// measureDrawer only converts units, drawing with it does nothing. It
+// lets a layouter compute its size before the actual drawer exists.
+type measureDrawer struct {
+	Dotter
+}
+
+// NewMeasureDrawer returns a Drawer which draws nothing at DPI.
+func NewMeasureDrawer(DPI int) Drawer {
+	return measureDrawer{Dotter{float64(DPI)}}
+}
+
+func (d measureDrawer) DrawRectangle(x, y, w, h int, c color.Color)   {}
+func (d measureDrawer) RotateTranslate(x, y int, r float64)           {}
+func (d measureDrawer) EndRotateTranslate()                           {}
+func (d measureDrawer) DrawLine(x1, y1, x2, y2, b int, c color.Color) {}
+func (d measureDrawer) DrawCircle(x, y, r, b int, c color.Color)      {}
+func (d measureDrawer) BeginLayer(l Layer)                            {}
+func (d measureDrawer) EndLayer()                                     {}
+func (d measureDrawer) BeginGroup(label string, m map[string]string)  {}
+func (d measureDrawer) EndGroup()                                     {}
+func (d measureDrawer) Close() error                                  { return nil }
+
+func (d measureDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	return 0.0
+}
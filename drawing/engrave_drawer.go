@@ -0,0 +1,337 @@
+package drawing
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// EngraveOptions configure the engraving outputs.
+type EngraveOptions struct {
+	// Spacing is the distance between two scan lines in mm.
+	Spacing float64
+	// Speed is the feed rate of the engraving moves in mm/min.
+	Speed float64
+	// Power is the spindle or laser power of the engraving moves.
+	Power float64
+	// Lines fills the black areas of DXF outputs with scan lines
+	// rather than closed polygons.
+	Lines bool
+}
+
+// fillOp is a rectangle painted in page coordinates.
+type fillOp struct {
+	bounds image.Rectangle
+	black  bool
+}
+
+// strokeOp is a line, or a circle if radius is strictly positive.
+type strokeOp struct {
+	layer          Layer
+	x1, y1, x2, y2 int
+	radius         int
+}
+
+// interval is a black run [x0,x1) of a scan line.
+type interval struct {
+	x0, x1 int
+}
+
+// fillBand is the part of a layer between two rows, where the black
+// intervals are the same for all the rows.
+type fillBand struct {
+	y0, y1    int
+	intervals []interval
+}
+
+// EngraveDrawer records the rectangles drawn in black, and writes
+// them as fill paths for an engraver: DXF polygons or scan lines, or
+// G-code scan lines. The rectangles are painted over each other in
+// order, on each layer separately. Lines and circles are written as
+// strokes, and labels are not engraved.
+type EngraveDrawer struct {
+	Dotter
+	layerStack
+	path          string
+	width, height int
+	opts          EngraveOptions
+
+	fills   map[Layer][]fillOp
+	strokes []strokeOp
+	x       []int
+	y       []int
+	// err is the first unsupported drawing, reported on Close
+	err error
+}
+
+// engraveWriters write the engraving outputs.
+var engraveWriters = map[string]func(d *EngraveDrawer, w *bufio.Writer) error{
+	".dxf":   writeDXF,
+	".gcode": writeGCode,
+	".nc":    writeGCode,
+	".ngc":   writeGCode,
+}
+
+// IsEngraveFile returns true if path has the extension of an
+// engraving output.
+func IsEngraveFile(path string) bool {
+	_, ok := engraveWriters[filepath.Ext(path)]
+	return ok
+}
+
+func NewEngraveDrawer(path string, width, height float64, DPI int, opts EngraveOptions) (Drawer, error) {
+	if _, ok := engraveWriters[filepath.Ext(path)]; ok == false {
+		return nil, fmt.Errorf("Unsupported engraving file extension '%s'", filepath.Ext(path))
+	}
+	if opts.Spacing <= 0.0 {
+		return nil, fmt.Errorf("Engraving line spacing must be strictly positive")
+	}
+	if opts.Speed <= 0.0 {
+		return nil, fmt.Errorf("Engraving speed must be strictly positive")
+	}
+	res := &EngraveDrawer{
+		Dotter: Dotter{float64(DPI)},
+		path:   path,
+		opts:   opts,
+		fills:  map[Layer][]fillOp{},
+	}
+	res.width = res.ToDot(width)
+	res.height = res.ToDot(height)
+	return res, nil
+}
+
+func (d *EngraveDrawer) Close() error {
+	if d.err != nil {
+		return d.err
+	}
+	f, err := os.Create(d.path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := engraveWriters[filepath.Ext(d.path)](d, w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (d *EngraveDrawer) Offsets() (int, int) {
+	if len(d.x) == 0 {
+		return 0, 0
+	}
+	return d.x[len(d.x)-1], d.y[len(d.y)-1]
+}
+
+func (d *EngraveDrawer) RotateTranslate(x, y int, angle float64) {
+	if angle != 0 && d.err == nil {
+		d.err = fmt.Errorf("Engraving outputs cannot draw rotated tags")
+	}
+	xo, yo := d.Offsets()
+	d.x = append(d.x, x+xo)
+	d.y = append(d.y, y+yo)
+}
+
+func (d *EngraveDrawer) EndRotateTranslate() {
+	if len(d.x) == 0 {
+		return
+	}
+	d.x = d.x[0:(len(d.x) - 1)]
+	d.y = d.y[0:(len(d.y) - 1)]
+}
+
+func (d *EngraveDrawer) BeginGroup(label string, metadata map[string]string) {}
+func (d *EngraveDrawer) EndGroup()                                           {}
+
+func (d *EngraveDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	xo, yo := d.Offsets()
+	bounds := image.Rect(x+xo, y+yo, x+xo+w, y+yo+h).Intersect(image.Rect(0, 0, d.width, d.height))
+	if bounds.Empty() == true {
+		return
+	}
+	l := d.Layer()
+	d.fills[l] = append(d.fills[l], fillOp{bounds: bounds, black: isBlack(c)})
+}
+
+func (d *EngraveDrawer) DrawLine(x1, y1, x2, y2, b int, c color.Color) {
+	xo, yo := d.Offsets()
+	d.strokes = append(d.strokes, strokeOp{layer: d.Layer(), x1: x1 + xo, y1: y1 + yo, x2: x2 + xo, y2: y2 + yo})
+}
+
+func (d *EngraveDrawer) DrawCircle(x, y, r, b int, c color.Color) {
+	xo, yo := d.Offsets()
+	d.strokes = append(d.strokes, strokeOp{layer: d.Layer(), x1: x + xo, y1: y + yo, radius: r})
+}
+
+func (d *EngraveDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	return 0.0
+}
+
+// bands returns the black intervals of layer l, from top to bottom.
+// The rows between two consecutive edges of the rectangles are all the
+// same, so each band is painted once.
+func (d *EngraveDrawer) bands(l Layer) []fillBand {
+	ops := d.fills[l]
+	edges := []int{}
+	for _, op := range ops {
+		edges = append(edges, op.bounds.Min.Y, op.bounds.Max.Y)
+	}
+	edges = sortUnique(edges)
+
+	// ops sorted by their first row, to sweep the bands
+	byTop := make([]int, len(ops))
+	for i := range byTop {
+		byTop[i] = i
+	}
+	sort.SliceStable(byTop, func(i, j int) bool {
+		return ops[byTop[i]].bounds.Min.Y < ops[byTop[j]].bounds.Min.Y
+	})
+
+	res := []fillBand{}
+	active := []int{}
+	next := 0
+	for i := 0; i+1 < len(edges); i++ {
+		y0, y1 := edges[i], edges[i+1]
+		kept := active[:0]
+		for _, j := range active {
+			if ops[j].bounds.Max.Y > y0 {
+				kept = append(kept, j)
+			}
+		}
+		active = kept
+		for ; next < len(byTop) && ops[byTop[next]].bounds.Min.Y <= y0; next++ {
+			active = append(active, byTop[next])
+		}
+		// paints in drawing order
+		sort.Ints(active)
+		res = append(res, fillBand{y0: y0, y1: y1, intervals: paintIntervals(ops, active)})
+	}
+	return res
+}
+
+func sortUnique(values []int) []int {
+	sort.Ints(values)
+	res := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// paintIntervals paints the ops at indices in order on a row, and
+// returns its black intervals.
+func paintIntervals(ops []fillOp, indices []int) []interval {
+	xs := []int{}
+	for _, i := range indices {
+		xs = append(xs, ops[i].bounds.Min.X, ops[i].bounds.Max.X)
+	}
+	xs = sortUnique(xs)
+	if len(xs) < 2 {
+		return nil
+	}
+	black := make([]bool, len(xs)-1)
+	for _, i := range indices {
+		start := sort.SearchInts(xs, ops[i].bounds.Min.X)
+		end := sort.SearchInts(xs, ops[i].bounds.Max.X)
+		for k := start; k < end; k++ {
+			black[k] = ops[i].black
+		}
+	}
+	res := []interval{}
+	for k, b := range black {
+		if b == false {
+			continue
+		}
+		if len(res) > 0 && res[len(res)-1].x1 == xs[k] {
+			res[len(res)-1].x1 = xs[k+1]
+			continue
+		}
+		res = append(res, interval{xs[k], xs[k+1]})
+	}
+	return res
+}
+
+// polygons merges the identical intervals of consecutive bands in
+// rectangles, which cover the black area without overlapping.
+func polygons(bands []fillBand) []image.Rectangle {
+	res := []image.Rectangle{}
+	open := map[interval]int{}
+	for i, b := range bands {
+		current := map[interval]bool{}
+		for _, iv := range b.intervals {
+			current[iv] = true
+			if _, ok := open[iv]; ok == false {
+				open[iv] = b.y0
+			}
+		}
+		end := i+1 == len(bands)
+		for iv, y0 := range open {
+			if current[iv] == true && end == false {
+				continue
+			}
+			if current[iv] == true {
+				res = append(res, image.Rect(iv.x0, y0, iv.x1, b.y1))
+			} else {
+				res = append(res, image.Rect(iv.x0, y0, iv.x1, b.y0))
+			}
+			delete(open, iv)
+		}
+	}
+	// the map iteration order is random
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Min.Y != res[j].Min.Y {
+			return res[i].Min.Y < res[j].Min.Y
+		}
+		return res[i].Min.X < res[j].Min.X
+	})
+	return res
+}
+
+// scanLines returns the black intervals of the scan lines, every
+// spacing mm starting half a spacing from the top, and their rows.
+func (d *EngraveDrawer) scanLines(bands []fillBand) ([]float64, [][]interval) {
+	rows := []float64{}
+	lines := [][]interval{}
+	spacing := d.opts.Spacing * d.dpi / anInch
+	b := 0
+	for y := spacing / 2; y < float64(d.height); y += spacing {
+		for b < len(bands) && float64(bands[b].y1) <= y {
+			b++
+		}
+		if b == len(bands) {
+			break
+		}
+		if float64(bands[b].y0) > y || len(bands[b].intervals) == 0 {
+			continue
+		}
+		rows = append(rows, y)
+		lines = append(lines, bands[b].intervals)
+	}
+	return rows, lines
+}
+
+// layers returns the layers with something to engrave, in order.
+func (d *EngraveDrawer) layers() []Layer {
+	res := []Layer{}
+	for i := range layerNames {
+		l := Layer(i)
+		has := len(d.fills[l]) > 0
+		for _, s := range d.strokes {
+			has = has || s.layer == l
+		}
+		if has == true {
+			res = append(res, l)
+		}
+	}
+	return res
+}
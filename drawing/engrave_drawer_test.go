@@ -0,0 +1,138 @@
+package drawing
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type EngraveSuite struct{}
+
+var _ = Suite(&EngraveSuite{})
+
+// drawEngraveScene draws at 254 DPI a 1mm black square with a hole
+// and a cut line.
+func drawEngraveScene(d Drawer) {
+	d.DrawRectangle(0, 0, 30, 20, color.White)
+	d.RotateTranslate(5, 5, 0)
+	d.DrawRectangle(0, 0, 10, 10, color.Black)
+	d.DrawRectangle(2, 2, 3, 3, color.White)
+	d.EndRotateTranslate()
+	d.BeginLayer(CutLayer)
+	d.DrawRectangle(20, 0, 1, 20, color.Black)
+	d.EndLayer()
+	d.Label(0, 0, 5, "not engraved", color.Black)
+}
+
+func (s *EngraveSuite) TestPaintsRectanglesInOrder(c *C) {
+	d, err := NewEngraveDrawer(filepath.Join(c.MkDir(), "scene.dxf"), 3, 2, 254, EngraveOptions{Spacing: 0.25, Speed: 100})
+	c.Assert(err, IsNil)
+	drawEngraveScene(d)
+	bands := d.(*EngraveDrawer).bands(TagLayer)
+	c.Check(bands, DeepEquals, []fillBand{
+		{0, 5, []interval{}},
+		{5, 7, []interval{{5, 15}}},
+		{7, 10, []interval{{5, 7}, {10, 15}}},
+		{10, 15, []interval{{5, 15}}},
+		{15, 20, []interval{}},
+	})
+	c.Check(polygons(bands), DeepEquals, []image.Rectangle{
+		image.Rect(5, 5, 15, 7),
+		image.Rect(5, 7, 7, 10),
+		image.Rect(10, 7, 15, 10),
+		image.Rect(5, 10, 15, 15),
+	})
+}
+
+func (s *EngraveSuite) TestDXF(c *C) {
+	dir := c.MkDir()
+	for _, lines := range []bool{false, true} {
+		path := filepath.Join(dir, "scene.dxf")
+		d, err := NewEngraveDrawer(path, 3, 2, 254, EngraveOptions{Spacing: 0.25, Speed: 100, Lines: lines})
+		c.Assert(err, IsNil)
+		drawEngraveScene(d)
+		c.Assert(d.Close(), IsNil)
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, IsNil)
+		content := string(data)
+		c.Check(strings.HasSuffix(content, "0\nENDSEC\n0\nEOF\n"), Equals, true)
+		// R12 only knows the version in the header
+		c.Check(strings.HasPrefix(content, "0\nSECTION\n2\nHEADER\n9\n$ACADVER\n1\nAC1009\n0\nENDSEC\n"), Equals, true)
+		c.Check(strings.Contains(content, "0\nLAYER\n2\ntags\n"), Equals, true)
+		c.Check(strings.Contains(content, "0\nLAYER\n2\ncuts\n"), Equals, true)
+		if lines == true {
+			c.Check(strings.Count(content, "0\nPOLYLINE\n"), Equals, 0)
+			// 4 scan lines, one with 2 intervals, and the cut line
+			c.Check(strings.Count(content, "0\nLINE\n8\ntags\n"), Equals, 5)
+			c.Check(strings.Count(content, "0\nLINE\n8\ncuts\n"), Equals, 8)
+		} else {
+			c.Check(strings.Count(content, "0\nPOLYLINE\n8\ntags\n"), Equals, 4)
+			c.Check(strings.Count(content, "0\nPOLYLINE\n8\ncuts\n"), Equals, 1)
+			// the Y axis points up
+			c.Check(strings.Contains(content, "0\nVERTEX\n8\ntags\n10\n0.5000\n20\n1.5000\n"), Equals, true)
+		}
+	}
+}
+
+func (s *EngraveSuite) TestGCode(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.gcode")
+	d, err := NewEngraveDrawer(path, 3, 2, 254, EngraveOptions{Spacing: 0.25, Speed: 600, Power: 300})
+	c.Assert(err, IsNil)
+	drawEngraveScene(d)
+	c.Assert(d.Close(), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	content := string(data)
+
+	moves := regexp.MustCompile(`(?m)^; layer tags\n((?:.*\n)*?); layer cuts`).FindStringSubmatch(content)
+	c.Assert(moves, NotNil)
+	c.Check(moves[1], Equals, `G0 X0.5000 Y1.3750
+M3 S300
+G1 X1.5000 F600
+M5
+G0 X1.5000 Y1.1250
+M3 S300
+G1 X1.0000 F600
+M5
+G0 X0.7000 Y1.1250
+M3 S300
+G1 X0.5000 F600
+M5
+G0 X0.5000 Y0.8750
+M3 S300
+G1 X1.5000 F600
+M5
+G0 X1.5000 Y0.6250
+M3 S300
+G1 X0.5000 F600
+M5
+`)
+	c.Check(strings.HasSuffix(content, "G0 X0 Y0\nM2\n"), Equals, true)
+}
+
+func (s *EngraveSuite) TestOptions(c *C) {
+	dir := c.MkDir()
+	_, err := NewEngraveDrawer(filepath.Join(dir, "scene.svg"), 3, 2, 254, EngraveOptions{Spacing: 0.25, Speed: 600})
+	c.Check(err, ErrorMatches, "Unsupported engraving file extension '.svg'")
+	_, err = NewEngraveDrawer(filepath.Join(dir, "scene.nc"), 3, 2, 254, EngraveOptions{Speed: 600})
+	c.Check(err, ErrorMatches, "Engraving line spacing must be strictly positive")
+	_, err = NewEngraveDrawer(filepath.Join(dir, "scene.nc"), 3, 2, 254, EngraveOptions{Spacing: 0.25})
+	c.Check(err, ErrorMatches, "Engraving speed must be strictly positive")
+}
+
+func (s *EngraveSuite) TestRejectsRotations(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.gcode")
+	d, err := NewEngraveDrawer(path, 3, 2, 254, EngraveOptions{Spacing: 0.25, Speed: 100})
+	c.Assert(err, IsNil)
+	d.RotateTranslate(5, 5, 30)
+	d.DrawRectangle(0, 0, 10, 10, color.Black)
+	d.EndRotateTranslate()
+	c.Check(d.Close(), ErrorMatches, "Engraving outputs cannot draw rotated tags")
+	_, err = ioutil.ReadFile(path)
+	c.Check(err, NotNil)
+}
@@ -0,0 +1,176 @@
+package drawing
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// mm converts a coordinate in dots to mm.
+func (d *EngraveDrawer) mm(v float64) float64 {
+	return v * anInch / d.dpi
+}
+
+// machineY converts a row in dots to mm from the bottom of the page,
+// as the Y axis of DXF and of engravers points up.
+func (d *EngraveDrawer) machineY(y float64) float64 {
+	return d.mm(float64(d.height) - y)
+}
+
+// dxfColors are the ACI colors of the layers.
+var dxfColors = []int{7, 1, 5, 3, 8}
+
+// writeDXF writes an ASCII DXF R12 in mm, with each layer as a DXF
+// layer. The black areas are closed polygons, or scan lines. R12 has
+// no header variable for the units, the importing software must be
+// set to mm.
+func writeDXF(d *EngraveDrawer, w *bufio.Writer) error {
+	pair := func(code int, value interface{}) {
+		if v, ok := value.(float64); ok == true {
+			value = fmt.Sprintf("%.4f", v)
+		}
+		fmt.Fprintf(w, "%d\n%v\n", code, value)
+	}
+	vertex := func(layer string, x, y float64) {
+		pair(0, "VERTEX")
+		pair(8, layer)
+		pair(10, x)
+		pair(20, y)
+		pair(30, 0.0)
+	}
+
+	pair(0, "SECTION")
+	pair(2, "HEADER")
+	pair(9, "$ACADVER")
+	pair(1, "AC1009")
+	pair(0, "ENDSEC")
+
+	layers := d.layers()
+	pair(0, "SECTION")
+	pair(2, "TABLES")
+	pair(0, "TABLE")
+	pair(2, "LAYER")
+	pair(70, len(layers))
+	for _, l := range layers {
+		pair(0, "LAYER")
+		pair(2, l.String())
+		pair(70, 0)
+		pair(62, dxfColors[l])
+		pair(6, "CONTINUOUS")
+	}
+	pair(0, "ENDTAB")
+	pair(0, "ENDSEC")
+
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	for _, l := range layers {
+		name := l.String()
+		bands := d.bands(l)
+		if d.opts.Lines == true {
+			rows, lines := d.scanLines(bands)
+			for i, y := range rows {
+				for _, iv := range lines[i] {
+					pair(0, "LINE")
+					pair(8, name)
+					pair(10, d.mm(float64(iv.x0)))
+					pair(20, d.machineY(y))
+					pair(30, 0.0)
+					pair(11, d.mm(float64(iv.x1)))
+					pair(21, d.machineY(y))
+					pair(31, 0.0)
+				}
+			}
+		} else {
+			for _, r := range polygons(bands) {
+				pair(0, "POLYLINE")
+				pair(8, name)
+				pair(66, 1)
+				pair(10, 0.0)
+				pair(20, 0.0)
+				pair(30, 0.0)
+				// closed
+				pair(70, 1)
+				x0, x1 := d.mm(float64(r.Min.X)), d.mm(float64(r.Max.X))
+				y0, y1 := d.machineY(float64(r.Min.Y)), d.machineY(float64(r.Max.Y))
+				vertex(name, x0, y0)
+				vertex(name, x1, y0)
+				vertex(name, x1, y1)
+				vertex(name, x0, y1)
+				pair(0, "SEQEND")
+				pair(8, name)
+			}
+		}
+		for _, s := range d.strokes {
+			if s.layer != l {
+				continue
+			}
+			if s.radius > 0 {
+				pair(0, "CIRCLE")
+				pair(8, name)
+				pair(10, d.mm(float64(s.x1)))
+				pair(20, d.machineY(float64(s.y1)))
+				pair(30, 0.0)
+				pair(40, d.mm(float64(s.radius)))
+				continue
+			}
+			pair(0, "LINE")
+			pair(8, name)
+			pair(10, d.mm(float64(s.x1)))
+			pair(20, d.machineY(float64(s.y1)))
+			pair(30, 0.0)
+			pair(11, d.mm(float64(s.x2)))
+			pair(21, d.machineY(float64(s.y2)))
+			pair(31, 0.0)
+		}
+	}
+	pair(0, "ENDSEC")
+	pair(0, "EOF")
+	return nil
+}
+
+// writeGCode writes the scan lines in mm, alternating their direction,
+// and the strokes. The spindle or laser is only on while engraving.
+func writeGCode(d *EngraveDrawer, w *bufio.Writer) error {
+	printf := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format, args...)
+	}
+	engrave := func(x0, y0 float64, move string) {
+		printf("G0 X%.4f Y%.4f\n", x0, y0)
+		printf("M3 S%g\n", d.opts.Power)
+		printf("%s F%g\n", move, d.opts.Speed)
+		printf("M5\n")
+	}
+
+	printf("; tag-layouter engraving of %.2fx%.2fmm, scan lines every %.4fmm\n",
+		d.mm(float64(d.width)), d.mm(float64(d.height)), d.opts.Spacing)
+	printf("G21\nG90\nM5\n")
+	for _, l := range d.layers() {
+		printf("; layer %s\n", l)
+		rows, lines := d.scanLines(d.bands(l))
+		for i, y := range rows {
+			intervals := lines[i]
+			for k := range intervals {
+				iv := intervals[k]
+				x0, x1 := d.mm(float64(iv.x0)), d.mm(float64(iv.x1))
+				if i%2 == 1 {
+					iv = intervals[len(intervals)-1-k]
+					x0, x1 = d.mm(float64(iv.x1)), d.mm(float64(iv.x0))
+				}
+				engrave(x0, d.machineY(y), fmt.Sprintf("G1 X%.4f", x1))
+			}
+		}
+		for _, s := range d.strokes {
+			if s.layer != l {
+				continue
+			}
+			x, y := d.mm(float64(s.x1)), d.machineY(float64(s.y1))
+			if s.radius > 0 {
+				r := d.mm(float64(s.radius))
+				engrave(x+r, y, fmt.Sprintf("G2 X%.4f Y%.4f I%.4f J0", x+r, y, -r))
+				continue
+			}
+			engrave(x, y, fmt.Sprintf("G1 X%.4f Y%.4f", d.mm(float64(s.x2)), d.machineY(float64(s.y2))))
+		}
+	}
+	printf("G0 X0 Y0\nM2\n")
+	return nil
+}
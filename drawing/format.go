@@ -0,0 +1,32 @@
+package drawing
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The PostScript and PDF outputs share the syntax of numbers, strings
+// and colors.
+
+// formatNumber writes v with at most 6 decimals.
+func formatNumber(v float64) string {
+	// adding zero avoids writing -0
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6+0, 'f', -1, 64)
+}
+
+// formatString escapes s as a literal string.
+func formatString(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s) + ")"
+}
+
+// formatColor returns the RGB components of c.
+func formatColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return strings.Join([]string{
+		formatNumber(float64(r) / 0xffff),
+		formatNumber(float64(g) / 0xffff),
+		formatNumber(float64(b) / 0xffff),
+	}, " ")
+}
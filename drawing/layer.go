@@ -0,0 +1,92 @@
+package drawing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layer groups the drawing operations by purpose, for them to be
+// separated in the output. Drawing happens on the TagLayer unless a
+// Drawer is told otherwise with BeginLayer.
+type Layer int
+
+const (
+	TagLayer Layer = iota
+	LabelLayer
+	CutLayer
+	GuideLayer
+	InfoLayer
+)
+
+var layerNames = []string{"tags", "labels", "cuts", "guides", "info"}
+
+func (l Layer) String() string {
+	if l < 0 || int(l) >= len(layerNames) {
+		return fmt.Sprintf("layer%d", int(l))
+	}
+	return layerNames[l]
+}
+
+// ExtractLayers parses a comma separated list of layer names, other
+// than the tags which are never separated.
+func ExtractLayers(s string) ([]Layer, error) {
+	res := []Layer{}
+	if len(strings.TrimSpace(s)) == 0 {
+		return res, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for i, n := range layerNames {
+			if n != name {
+				continue
+			}
+			found = true
+			l := Layer(i)
+			if l == TagLayer {
+				return nil, fmt.Errorf("The tags layer cannot be separated")
+			}
+			if ContainsLayer(res, l) == false {
+				res = append(res, l)
+			}
+		}
+		if found == false {
+			return nil, fmt.Errorf("Unknown layer '%s', expected one of %s", name, strings.Join(layerNames[1:], ", "))
+		}
+	}
+	return res, nil
+}
+
+// ContainsLayer returns true if l is one of layers.
+func ContainsLayer(layers []Layer, l Layer) bool {
+	for _, ll := range layers {
+		if ll == l {
+			return true
+		}
+	}
+	return false
+}
+
+// layerStack implements BeginLayer and EndLayer for a Drawer.
+type layerStack struct {
+	layers []Layer
+}
+
+func (s *layerStack) BeginLayer(l Layer) {
+	s.layers = append(s.layers, l)
+}
+
+func (s *layerStack) EndLayer() {
+	if len(s.layers) == 0 {
+		return
+	}
+	s.layers = s.layers[:len(s.layers)-1]
+}
+
+// Layer returns the current layer.
+func (s *layerStack) Layer() Layer {
+	if len(s.layers) == 0 {
+		return TagLayer
+	}
+	return s.layers[len(s.layers)-1]
+}
@@ -0,0 +1,43 @@
+package drawing
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type LayerSuite struct{}
+
+var _ = Suite(&LayerSuite{})
+
+func (s *LayerSuite) TestExtractLayers(c *C) {
+	testdata := []struct {
+		Input    string
+		Expected []Layer
+	}{
+		{"", []Layer{}},
+		{"labels", []Layer{LabelLayer}},
+		{"Cuts, labels", []Layer{CutLayer, LabelLayer}},
+		{"guides,info,guides", []Layer{GuideLayer, InfoLayer}},
+	}
+	for _, d := range testdata {
+		res, err := ExtractLayers(d.Input)
+		if c.Check(err, IsNil, Commentf("for %s", d.Input)) == false {
+			continue
+		}
+		c.Check(res, DeepEquals, d.Expected, Commentf("for %s", d.Input))
+	}
+
+	_, err := ExtractLayers("tags")
+	c.Check(err, ErrorMatches, "The tags layer cannot be separated")
+	_, err = ExtractLayers("labels,foo")
+	c.Check(err, ErrorMatches, "Unknown layer 'foo', expected one of labels, cuts, guides, info")
+}
+
+func (s *LayerSuite) TestLayerPath(c *C) {
+	c.Check(LayerPath("out/sheet.png", LabelLayer), Equals, "out/sheet_labels.png")
+	c.Check(LayerPath("sheet.tiff", CutLayer), Equals, "sheet_cuts.tiff")
+}
@@ -0,0 +1,210 @@
+package drawing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PDFDrawer writes a single page PDF, where each layer is an optional
+// content group which viewers can hide, and each group a marked
+// content sequence with its metadata. Labels use the standard
+// Courier font, which is not embedded.
+type PDFDrawer struct {
+	Dotter
+	layerStack
+	f             io.WriteCloser
+	width, height int
+
+	content bytes.Buffer
+	// marked is the layer of the open marked content, if any
+	marked    Layer
+	hasMarked bool
+	used      map[Layer]bool
+}
+
+// courierAdvance is the advance of a Courier glyph in em.
+const courierAdvance = 0.6
+
+func NewPDFDrawer(filepath string, width, height float64, DPI int) (Drawer, error) {
+	f, err := os.Create(filepath)
+	if err != nil {
+		return nil, err
+	}
+	res := &PDFDrawer{
+		Dotter: Dotter{float64(DPI)},
+		f:      f,
+		used:   map[Layer]bool{},
+	}
+	res.width = res.ToDot(width)
+	res.height = res.ToDot(height)
+	// draws in dots from the top left corner
+	scale := 72.0 / res.dpi
+	res.printf("%s 0 0 %s 0 %s cm\n", formatNumber(scale), formatNumber(-scale), formatNumber(float64(res.height)*scale))
+	return res, nil
+}
+
+func (d *PDFDrawer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&d.content, format, args...)
+}
+
+// mark opens the marked content of the current layer, if not already.
+func (d *PDFDrawer) mark() {
+	if d.hasMarked == true && d.marked == d.Layer() {
+		return
+	}
+	d.unmark()
+	d.printf("/OC /L%d BDC\n", int(d.Layer()))
+	d.marked, d.hasMarked = d.Layer(), true
+	d.used[d.Layer()] = true
+}
+
+// unmark closes the marked content, which must be nested within the
+// graphic states.
+func (d *PDFDrawer) unmark() {
+	if d.hasMarked == false {
+		return
+	}
+	d.printf("EMC\n")
+	d.hasMarked = false
+}
+
+func (d *PDFDrawer) RotateTranslate(x, y int, angle float64) {
+	d.unmark()
+	radians := angle * math.Pi / 180.0
+	cos, sin := math.Cos(radians), math.Sin(radians)
+	d.printf("q %s %s %s %s %d %d cm\n", formatNumber(cos), formatNumber(sin), formatNumber(-sin), formatNumber(cos), x, y)
+}
+
+func (d *PDFDrawer) EndRotateTranslate() {
+	d.unmark()
+	d.printf("Q\n")
+}
+
+// BeginGroup opens a marked content sequence tagged Block, with the
+// label and metadata in its property list. The layers are marked
+// within it.
+func (d *PDFDrawer) BeginGroup(label string, metadata map[string]string) {
+	d.unmark()
+	properties := "/Label " + formatString(label)
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		properties += " " + pdfName(k) + " " + formatString(metadata[k])
+	}
+	d.printf("/Block << %s >> BDC\n", properties)
+}
+
+func (d *PDFDrawer) EndGroup() {
+	d.unmark()
+	d.printf("EMC\n")
+}
+
+// pdfName returns s as a name, where delimiters, whitespaces and
+// non printable characters are written in hexadecimal.
+func pdfName(s string) string {
+	res := "/"
+	for _, c := range []byte(s) {
+		if c <= ' ' || c > '~' || strings.IndexByte("#%()/<>[]{}", c) >= 0 {
+			res += fmt.Sprintf("#%02X", c)
+		} else {
+			res += string(c)
+		}
+	}
+	return res
+}
+
+func (d *PDFDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	d.mark()
+	d.printf("%s rg %d %d %d %d re f\n", formatColor(c), x, y, w, h)
+}
+
+func (d *PDFDrawer) DrawLine(x1, y1, x2, y2, border int, c color.Color) {
+	d.mark()
+	d.printf("%d w %s RG %d %d m %d %d l S\n", border, formatColor(c), x1, y1, x2, y2)
+}
+
+func (d *PDFDrawer) DrawCircle(x, y, radius, border int, c color.Color) {
+	d.mark()
+	// four cubic Bézier arcs
+	r := float64(radius)
+	k := 0.5523 * r
+	fx, fy := float64(x), float64(y)
+	p := func(v float64) string { return formatNumber(v) }
+	d.printf("%d w %s RG %s %s m\n", border, formatColor(c), p(fx+r), p(fy))
+	d.printf("%s %s %s %s %s %s c\n", p(fx+r), p(fy+k), p(fx+k), p(fy+r), p(fx), p(fy+r))
+	d.printf("%s %s %s %s %s %s c\n", p(fx-k), p(fy+r), p(fx-r), p(fy+k), p(fx-r), p(fy))
+	d.printf("%s %s %s %s %s %s c\n", p(fx-r), p(fy-k), p(fx-k), p(fy-r), p(fx), p(fy-r))
+	d.printf("%s %s %s %s %s %s c S\n", p(fx+k), p(fy-r), p(fx+r), p(fy-k), p(fx+r), p(fy))
+}
+
+func (d *PDFDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	d.mark()
+	// the text matrix flips the glyphs back
+	d.printf("BT /F1 %d Tf 1 0 0 -1 %d %d Tm %s rg %s Tj ET\n", height, x, y+height, formatColor(c), formatString(label))
+	return courierAdvance * float64(len(label)) * d.ToMM(height)
+}
+
+func (d *PDFDrawer) Close() error {
+	d.unmark()
+	stream := bytes.NewBuffer(nil)
+	zw := zlib.NewWriter(stream)
+	zw.Write(d.content.Bytes())
+	zw.Close()
+
+	layers := []Layer{}
+	for i := range layerNames {
+		if d.used[Layer(i)] == true {
+			layers = append(layers, Layer(i))
+		}
+	}
+	ocgs := []string{}
+	properties := ""
+	for i, l := range layers {
+		ocgs = append(ocgs, fmt.Sprintf("%d 0 R", 6+i))
+		properties += fmt.Sprintf(" /L%d %d 0 R", int(l), 6+i)
+	}
+	scale := 72.0 / d.dpi
+
+	objects := []string{
+		fmt.Sprintf("<< /Type /Catalog /Pages 2 0 R /OCProperties << /OCGs [%s] /D << /Order [%s] /ON [%s] >> >> >>",
+			strings.Join(ocgs, " "), strings.Join(ocgs, " "), strings.Join(ocgs, " ")),
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 5 0 R >> /Properties <<%s >> >> /Contents 4 0 R >>",
+			formatNumber(float64(d.width)*scale), formatNumber(float64(d.height)*scale), properties),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	}
+	for _, l := range layers {
+		objects = append(objects, fmt.Sprintf("<< /Type /OCG /Name %s >>", formatString(l.String())))
+	}
+
+	out := bytes.NewBuffer(nil)
+	out.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	offsets := []int{}
+	for i, o := range objects {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	if _, err := out.WriteTo(d.f); err != nil {
+		d.f.Close()
+		return err
+	}
+	return d.f.Close()
+}
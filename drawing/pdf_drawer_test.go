@@ -0,0 +1,63 @@
+package drawing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *VectorDrawerSuite) TestPDFLayers(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.pdf")
+	d, err := NewPDFDrawer(path, 20, 10, 254)
+	c.Assert(err, IsNil)
+	drawTestBlock(d)
+	c.Assert(d.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+
+	// the cross reference table points to the objects
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	c.Assert(m, NotNil)
+	xref, err := strconv.Atoi(string(m[1]))
+	c.Assert(err, IsNil)
+	c.Assert(bytes.HasPrefix(data[xref:], []byte("xref\n0 10\n")), Equals, true)
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	c.Assert(entries, HasLen, 9)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		c.Check(bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), Equals, true)
+	}
+
+	names := regexp.MustCompile(`/Type /OCG /Name \((\w+)\)`).FindAllSubmatch(data, -1)
+	c.Assert(names, HasLen, 4)
+	for i, expected := range []string{"tags", "labels", "cuts", "guides"} {
+		c.Check(string(names[i][1]), Equals, expected)
+	}
+
+	m = regexp.MustCompile(`(?s)stream\n(.*)\nendstream`).FindSubmatch(data)
+	c.Assert(m, NotNil)
+	zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+	c.Assert(err, IsNil)
+	content, err := ioutil.ReadAll(zr)
+	c.Assert(err, IsNil)
+	count := func(expr string) int {
+		return len(regexp.MustCompile(`(?m)`+expr).FindAll(content, -1))
+	}
+	c.Check(count(`^q `), Equals, 3)
+	c.Check(count(`^Q$`), Equals, 3)
+	// the layers change 7 times, out of the graphic states, within
+	// the block of the group
+	c.Check(count(`^/OC /L\d BDC$`), Equals, 7)
+	c.Check(count(` BDC$`), Equals, 8)
+	c.Check(count(`^EMC$`), Equals, 8)
+	c.Check(bytes.Index(content, []byte("/Block")) < bytes.Index(content, []byte("/OC")), Equals, true)
+	c.Check(count(`^/Block << /Label \(36H11 3\.00MM 0-9\) /family \(36H11\) /ranges \(0-9\) >> BDC$`), Equals, 1)
+	c.Check(bytes.Contains(content, []byte("1 0 0 rg (42 TAGS) Tj")), Equals, true)
+}
@@ -0,0 +1,363 @@
+package drawing
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// ImageOptions select the backing store of an ImageDrawer.
+type ImageOptions struct {
+	// Mode is the pixel format. BilevelMode is written as a CCITT
+	// Group 4 TIFF or a 1 bit PNG. Nothing is drawn on the transparent
+	// background of RGBAMode.
+	Mode RasterMode
+	// Separate are the layers drawn in their own image, written next
+	// to the main one with the layer name as suffix.
+	Separate []Layer
+	// Jobs is the number of bands rendered concurrently, all the CPUs
+	// if not strictly positive.
+	Jobs int
+	// Context stops the rendering of the bands once done, and Close
+	// then fails with its error. nil is never done.
+	Context context.Context
+}
+
+// bandMemory is the maximal size in bytes of a rendered band.
+const bandMemory = 16 << 20
+
+// drawOp is a drawing operation in page coordinates on layer, which
+// only touches the pixels within bounds.
+type drawOp struct {
+	bounds image.Rectangle
+	layer  Layer
+	draw   func(d *rasterDrawer)
+}
+
+// imageOutput is an image file written band by band.
+type imageOutput struct {
+	f       *os.File
+	encoder bandEncoder
+}
+
+// ImageDrawer records the drawing operations, and renders them on
+// Close in bands of the page, which are streamed to the encoder. The
+// memory used is bounded by the size of a band rather than the size of
+// the page. The recorded operations are only read while the bands are
+// rendered, so bands are rendered concurrently.
+type ImageDrawer struct {
+	Dotter
+	layerStack
+	bounds image.Rectangle
+	opts   ImageOptions
+	// bandHeight is the number of rows rendered at once
+	bandHeight int
+
+	// outputs are the main image, then the separated layers in order
+	outputs []imageOutput
+	font    *truetype.Font
+	measure *rasterDrawer
+	ops     []drawOp
+	x       []int
+	y       []int
+	// err is the first unsupported drawing, reported on Close
+	err error
+}
+
+// LayerPath returns the path of the image of a separated layer,
+// written next to the image at path.
+func LayerPath(path string, l Layer) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + l.String() + ext
+}
+
+func newImageOutput(path string, factory bandEncoderFactory, bounds image.Rectangle, DPI float64, mode RasterMode) (imageOutput, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return imageOutput{}, err
+	}
+	encoder, err := factory(f, bounds.Dx(), bounds.Dy(), DPI, mode)
+	if err != nil {
+		f.Close()
+		return imageOutput{}, err
+	}
+	return imageOutput{f: f, encoder: encoder}, nil
+}
+
+func NewImageDrawer(filepath string, width, height float64, DPI int, opts ImageOptions) (Drawer, error) {
+	factory, err := matchEncoder(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ImageDrawer{
+		Dotter: Dotter{float64(DPI)},
+		opts:   opts,
+	}
+	res.bounds = image.Rect(0, 0, res.ToDot(width), res.ToDot(height))
+	stride := res.bounds.Dx()
+	switch opts.Mode {
+	case BilevelMode:
+		stride = (stride + 7) / 8
+	case RGBMode:
+		stride = 3 * stride
+	case RGBAMode:
+		stride = 4 * stride
+	}
+	res.bandHeight = max(1, bandMemory/max(1, stride))
+
+	res.font, err = parseMonoFont()
+	if err != nil {
+		return nil, err
+	}
+	// labels are measured when recorded, without drawing them
+	res.measure = newRasterDrawer(res.dpi, res.font, NewBilevel(image.Rectangle{}), nil)
+
+	paths := []string{filepath}
+	for _, l := range opts.Separate {
+		paths = append(paths, LayerPath(filepath, l))
+	}
+	for _, path := range paths {
+		output, err := newImageOutput(path, factory, res.bounds, res.dpi, opts.Mode)
+		if err != nil {
+			res.removeOutputs()
+			return nil, err
+		}
+		res.outputs = append(res.outputs, output)
+	}
+	return res, nil
+}
+
+// removeOutputs closes and removes the outputs already created, so a
+// failure does not leave half written images behind.
+func (d *ImageDrawer) removeOutputs() {
+	for _, o := range d.outputs {
+		o.f.Close()
+		os.Remove(o.f.Name())
+	}
+	d.outputs = nil
+}
+
+// bandOps lists, for each band, the operations touching it in the
+// order they were recorded. Every operation is visited once, rather
+// than once per band.
+func (d *ImageDrawer) bandOps(bands []image.Rectangle) [][]*drawOp {
+	res := make([][]*drawOp, len(bands))
+	for i := range d.ops {
+		op := &d.ops[i]
+		b := op.bounds.Intersect(d.bounds)
+		if b.Empty() == true {
+			continue
+		}
+		first := (b.Min.Y - d.bounds.Min.Y) / d.bandHeight
+		last := (b.Max.Y - 1 - d.bounds.Min.Y) / d.bandHeight
+		for j := first; j <= last; j++ {
+			res[j] = append(res[j], op)
+		}
+	}
+	return res
+}
+
+// renderBand replays ops, the operations touching band. It returns a
+// raster per output.
+func (d *ImageDrawer) renderBand(band image.Rectangle, ops []*drawOp) []raster {
+	res := []raster{newRaster(band, d.opts.Mode)}
+	separated := map[Layer]raster{}
+	for _, l := range d.opts.Separate {
+		// separated layers are drawn on a blank sheet
+		r := newRaster(band, d.opts.Mode)
+		r.FillRect(band, color.White)
+		separated[l] = r
+		res = append(res, r)
+	}
+	r := newRasterDrawer(d.dpi, d.font, res[0], separated)
+	for _, op := range ops {
+		r.BeginLayer(op.layer)
+		op.draw(r)
+		r.EndLayer()
+	}
+	return res
+}
+
+// encodedBand is a band compressed by the encoder of each output.
+type encodedBand struct {
+	rows int
+	data [][]byte
+	err  error
+}
+
+func (d *ImageDrawer) encodeBand(band image.Rectangle, ops []*drawOp) encodedBand {
+	res := encodedBand{rows: band.Dy()}
+	if d.opts.Context != nil && d.opts.Context.Err() != nil {
+		// the remaining bands are skipped
+		res.err = d.opts.Context.Err()
+		return res
+	}
+	for i, r := range d.renderBand(band, ops) {
+		data, err := d.outputs[i].encoder.Compress(r)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.data = append(res.data, data)
+	}
+	return res
+}
+
+func (d *ImageDrawer) writeBand(b encodedBand) error {
+	if b.err != nil {
+		return b.err
+	}
+	for i, o := range d.outputs {
+		if err := o.encoder.Write(b.rows, b.data[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBands renders and compresses the bands concurrently, and
+// writes them in order, so the output does not depend on the number
+// of jobs. At most two bands per job are held in memory.
+func (d *ImageDrawer) writeBands() error {
+	jobs := d.opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	bands := []image.Rectangle{}
+	for y := d.bounds.Min.Y; y < d.bounds.Max.Y; y += d.bandHeight {
+		bands = append(bands, image.Rect(d.bounds.Min.X, y, d.bounds.Max.X, min(y+d.bandHeight, d.bounds.Max.Y)))
+	}
+	ops := d.bandOps(bands)
+
+	results := make([]chan encodedBand, len(bands))
+	for i := range results {
+		results[i] = make(chan encodedBand, 1)
+	}
+	inFlight := make(chan struct{}, 2*jobs)
+	todo := make(chan int)
+	go func() {
+		for i := range bands {
+			inFlight <- struct{}{}
+			todo <- i
+		}
+		close(todo)
+	}()
+	for j := 0; j < jobs; j++ {
+		go func() {
+			for i := range todo {
+				results[i] <- d.encodeBand(bands[i], ops[i])
+			}
+		}()
+	}
+
+	var err error = nil
+	for i := range bands {
+		b := <-results[i]
+		// keeps receiving on error, for the workers to terminate
+		if err == nil {
+			err = d.writeBand(b)
+		}
+		<-inFlight
+	}
+	return err
+}
+
+func (d *ImageDrawer) Close() error {
+	if d.err != nil {
+		d.removeOutputs()
+		return d.err
+	}
+	if err := d.writeBands(); err != nil {
+		d.removeOutputs()
+		return err
+	}
+	for _, o := range d.outputs {
+		if err := o.encoder.Close(); err != nil {
+			d.removeOutputs()
+			return err
+		}
+		if err := o.f.Close(); err != nil {
+			d.removeOutputs()
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *ImageDrawer) BeginGroup(label string, metadata map[string]string) {}
+func (d *ImageDrawer) EndGroup()                                           {}
+
+func (d *ImageDrawer) Offsets() (int, int) {
+	if len(d.x) == 0 {
+		return 0, 0
+	}
+	return d.x[len(d.x)-1], d.y[len(d.y)-1]
+}
+
+func (d *ImageDrawer) record(bounds image.Rectangle, draw func(r *rasterDrawer)) {
+	d.ops = append(d.ops, drawOp{bounds: bounds, layer: d.Layer(), draw: draw})
+}
+
+func (d *ImageDrawer) DrawCircle(x, y, r, hb int, c color.Color) {
+	xo, yo := d.Offsets()
+	x, y = x+xo, y+yo
+	extent := r + hb + 1
+	d.record(image.Rect(x-extent, y-extent, x+extent+1, y+extent+1), func(rd *rasterDrawer) {
+		rd.DrawCircle(x, y, r, hb, c)
+	})
+}
+
+func (d *ImageDrawer) DrawLine(x1, y1, x2, y2, b int, c color.Color) {
+	xo, yo := d.Offsets()
+	x1, y1, x2, y2 = x1+xo, y1+yo, x2+xo, y2+yo
+	bounds := image.Rect(x1, y1, x2, y2)
+	bounds.Max = bounds.Max.Add(image.Pt(1, 1))
+	d.record(bounds, func(r *rasterDrawer) {
+		r.DrawLine(x1, y1, x2, y2, b, c)
+	})
+}
+
+func (d *ImageDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	xo, yo := d.Offsets()
+	bounds := image.Rect(x+xo, y+yo, x+xo+w, y+yo+h)
+	d.record(bounds, func(r *rasterDrawer) {
+		r.target().FillRect(bounds, c)
+	})
+}
+
+func (d *ImageDrawer) RotateTranslate(x, y int, angle float64) {
+	if angle != 0 && d.err == nil {
+		d.err = fmt.Errorf("Image outputs cannot draw rotated tags")
+	}
+	xo, yo := d.Offsets()
+	d.x = append(d.x, x+xo)
+	d.y = append(d.y, y+yo)
+}
+
+func (d *ImageDrawer) EndRotateTranslate() {
+	if len(d.x) == 0 {
+		return
+	}
+	d.x = d.x[0:(len(d.x) - 1)]
+	d.y = d.y[0:(len(d.y) - 1)]
+}
+
+func (d *ImageDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	xo, yo := d.Offsets()
+	x, y = x+xo, y+yo
+	width := d.measure.Label(x, y, height, label, c)
+	// glyphs may overflow the label box, which is one height tall
+	bounds := image.Rect(x-height, y-height, x+d.ToDot(width)+height, y+3*height)
+	d.record(bounds, func(r *rasterDrawer) {
+		r.Label(x, y, height, label, c)
+	})
+	return width
+}
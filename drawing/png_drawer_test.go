@@ -0,0 +1,253 @@
+package drawing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type ImageDrawerSuite struct{}
+
+var _ = Suite(&ImageDrawerSuite{})
+
+func drawTestScene(d Drawer) {
+	d.DrawRectangle(0, 0, 120, 90, color.White)
+	d.RotateTranslate(10, 5, 0)
+	d.DrawRectangle(0, 0, 30, 30, color.Black)
+	d.DrawRectangle(5, 5, 20, 20, color.White)
+	d.BeginLayer(CutLayer)
+	d.DrawRectangle(32, 0, 2, 30, color.Black)
+	d.EndLayer()
+	d.RotateTranslate(40, 20, 0)
+	d.BeginLayer(LabelLayer)
+	d.Label(0, 0, 25, "42 TAGS", color.RGBA{0xff, 0x00, 0x00, 0xff})
+	d.EndLayer()
+	d.EndRotateTranslate()
+	d.DrawCircle(60, 60, 12, 2, color.Black)
+	d.EndRotateTranslate()
+	d.DrawLine(3, 85, 110, 40, 1, color.Black)
+}
+
+func (s *ImageDrawerSuite) TestBandsMatchInMemoryRendering(c *C) {
+	font, err := parseMonoFont()
+	c.Assert(err, IsNil)
+	bounds := image.Rect(0, 0, 120, 90)
+	testdata := []struct {
+		ext  string
+		opts ImageOptions
+	}{
+		{".png", ImageOptions{}},
+		{".png", ImageOptions{Mode: BilevelMode}},
+		{".png", ImageOptions{Mode: BilevelMode, Separate: []Layer{LabelLayer}}},
+		{".png", ImageOptions{Mode: RGBMode}},
+		{".tiff", ImageOptions{Mode: RGBMode, Separate: []Layer{CutLayer, LabelLayer}}},
+		{".png", ImageOptions{Mode: RGBAMode}},
+		{".tiff", ImageOptions{Mode: RGBAMode}},
+	}
+	for _, d := range testdata {
+		// at 254 DPI, a dot is 0.1mm
+		separated := map[Layer]raster{}
+		for _, l := range d.opts.Separate {
+			separated[l] = newRaster(bounds, d.opts.Mode)
+			separated[l].FillRect(bounds, color.White)
+		}
+		expected := newRasterDrawer(254, font, newRaster(bounds, d.opts.Mode), separated)
+		drawTestScene(expected)
+
+		path := filepath.Join(c.MkDir(), "scene"+d.ext)
+		drawer, err := NewImageDrawer(path, 12, 9, 254, d.opts)
+		c.Assert(err, IsNil)
+		drawer.(*ImageDrawer).bandHeight = 7
+		drawTestScene(drawer)
+		c.Assert(drawer.Close(), IsNil)
+
+		checkSameRaster(c, path, expected.data)
+		for _, l := range d.opts.Separate {
+			checkSameRaster(c, LayerPath(path, l), separated[l])
+		}
+	}
+}
+
+func (s *ImageDrawerSuite) TestLabelsKeepTheirColorInRGB(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.png")
+	drawer, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{Mode: RGBMode})
+	c.Assert(err, IsNil)
+	drawTestScene(drawer)
+	c.Assert(drawer.Close(), IsNil)
+
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	c.Assert(err, IsNil)
+	colors := map[color.RGBA]bool{}
+	for y := decoded.Bounds().Min.Y; y < decoded.Bounds().Max.Y; y++ {
+		for x := decoded.Bounds().Min.X; x < decoded.Bounds().Max.X; x++ {
+			colors[color.RGBAModel.Convert(decoded.At(x, y)).(color.RGBA)] = true
+		}
+	}
+	c.Check(colors, DeepEquals, map[color.RGBA]bool{
+		{0x00, 0x00, 0x00, 0xff}: true,
+		{0xff, 0xff, 0xff, 0xff}: true,
+		{0xff, 0x00, 0x00, 0xff}: true,
+	})
+}
+
+func checkSameRaster(c *C, path string, expected raster) {
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	decoded, _, err := image.Decode(f)
+	c.Assert(err, IsNil)
+	c.Assert(decoded.Bounds(), Equals, expected.Bounds())
+	for y := expected.Bounds().Min.Y; y < expected.Bounds().Max.Y; y++ {
+		for x := expected.Bounds().Min.X; x < expected.Bounds().Max.X; x++ {
+			got := color.RGBAModel.Convert(decoded.At(x, y)).(color.RGBA)
+			want := color.RGBAModel.Convert(expected.At(x, y)).(color.RGBA)
+			if got != want {
+				c.Fatalf("%s: pixel (%d,%d) is %v, expected %v", path, x, y, got, want)
+			}
+		}
+	}
+}
+
+func (s *ImageDrawerSuite) TestOutputDoesNotDependOnJobs(c *C) {
+	dir := c.MkDir()
+	for _, ext := range []string{".png", ".tiff"} {
+		for _, mode := range []RasterMode{GrayMode, BilevelMode, RGBMode, RGBAMode} {
+			outputs := [][]byte{}
+			for _, jobs := range []int{1, 4} {
+				path := filepath.Join(dir, fmt.Sprintf("scene-%d%s", jobs, ext))
+				drawer, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{Mode: mode, Jobs: jobs})
+				c.Assert(err, IsNil)
+				drawer.(*ImageDrawer).bandHeight = 5
+				drawTestScene(drawer)
+				c.Assert(drawer.Close(), IsNil)
+				data, err := ioutil.ReadFile(path)
+				c.Assert(err, IsNil)
+				outputs = append(outputs, data)
+			}
+			c.Check(bytes.Equal(outputs[0], outputs[1]), Equals, true, Commentf("%s mode: %d", ext, mode))
+		}
+	}
+}
+
+func (s *ImageDrawerSuite) TestRemovesOutputsOnError(c *C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "sheet.png")
+	// a directory takes the place of the cut layer image
+	c.Assert(os.Mkdir(LayerPath(path, CutLayer), 0755), IsNil)
+	_, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{Separate: []Layer{LabelLayer, CutLayer}})
+	c.Check(err, NotNil)
+	_, err = os.Stat(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(LayerPath(path, LabelLayer))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ImageDrawerSuite) TestOperationsAreBucketedByBand(c *C) {
+	d := &ImageDrawer{bounds: image.Rect(0, 0, 100, 30), bandHeight: 10}
+	d.ops = []drawOp{
+		{bounds: image.Rect(0, 0, 10, 10)},
+		{bounds: image.Rect(0, 5, 10, 25)},
+		{bounds: image.Rect(0, -10, 10, 0)},
+		{bounds: image.Rect(0, 28, 10, 40)},
+	}
+	bands := []image.Rectangle{
+		image.Rect(0, 0, 100, 10),
+		image.Rect(0, 10, 100, 20),
+		image.Rect(0, 20, 100, 30),
+	}
+	ops := d.bandOps(bands)
+	c.Assert(ops, HasLen, 3)
+	c.Check(ops[0], DeepEquals, []*drawOp{&d.ops[0], &d.ops[1]})
+	c.Check(ops[1], DeepEquals, []*drawOp{&d.ops[1]})
+	c.Check(ops[2], DeepEquals, []*drawOp{&d.ops[1], &d.ops[3]})
+}
+
+func (s *ImageDrawerSuite) TestPNGBandsFormASingleStream(c *C) {
+	r := newRaster(image.Rect(0, 0, 37, 23), GrayMode)
+	for y := 0; y < 23; y++ {
+		for x := 0; x < 37; x++ {
+			r.Set(x, y, color.Gray{uint8(7*x + x*y)})
+		}
+	}
+	data := encodeBands(c, ".png", r, 254, GrayMode, 4)
+
+	idat := []byte{}
+	for rest := data[len(pngSignature):]; len(rest) > 0; {
+		size := binary.BigEndian.Uint32(rest)
+		if string(rest[4:8]) == "IDAT" {
+			idat = append(idat, rest[8:8+size]...)
+		}
+		rest = rest[12+size:]
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(idat))
+	c.Assert(err, IsNil)
+	// reading up to EOF verifies the checksum
+	rows, err := ioutil.ReadAll(zr)
+	c.Assert(err, IsNil)
+	encoder := &pngEncoder{mode: GrayMode, stride: 37, bpp: 1}
+	c.Check(bytes.Equal(rows, encoder.filter(r)), Equals, true)
+}
+
+func (s *ImageDrawerSuite) TestCombinesChecksums(c *C) {
+	data := bytes.Repeat([]byte("tag layouter "), 10000)
+	for _, split := range []int{0, 1, 65521, 70000, len(data)} {
+		first, second := data[:split], data[split:]
+		combined := adler32Combine(adler32.Checksum(first), adler32.Checksum(second), uint32(len(second)))
+		c.Check(combined, Equals, adler32.Checksum(data), Commentf("split: %d", split))
+	}
+}
+
+func (s *ImageDrawerSuite) TestRejectsRotations(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.png")
+	d, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{})
+	c.Assert(err, IsNil)
+	d.RotateTranslate(5, 5, 30)
+	d.DrawRectangle(0, 0, 10, 10, color.Black)
+	d.EndRotateTranslate()
+	c.Check(d.Close(), ErrorMatches, "Image outputs cannot draw rotated tags")
+	_, err = os.Stat(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *ImageDrawerSuite) TestRemovesOutputsWhenWritingFails(c *C) {
+	for _, ext := range []string{".png", ".tiff"} {
+		path := filepath.Join(c.MkDir(), "scene"+ext)
+		d, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{Separate: []Layer{LabelLayer}})
+		c.Assert(err, IsNil)
+		drawTestScene(d)
+		// the separated layer can no longer be written
+		c.Assert(d.(*ImageDrawer).outputs[1].f.Close(), IsNil)
+		c.Check(d.Close(), NotNil, Commentf("extension: %s", ext))
+		for _, p := range []string{path, LayerPath(path, LabelLayer)} {
+			_, err = os.Stat(p)
+			c.Check(os.IsNotExist(err), Equals, true, Commentf("path: %s", p))
+		}
+	}
+}
+
+func (s *ImageDrawerSuite) TestStopsWhenCancelled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	path := filepath.Join(c.MkDir(), "scene.png")
+	d, err := NewImageDrawer(path, 12, 9, 254, ImageOptions{Context: ctx})
+	c.Assert(err, IsNil)
+	d.(*ImageDrawer).bandHeight = 5
+	drawTestScene(d)
+	cancel()
+	c.Check(d.Close(), Equals, context.Canceled)
+	_, err = os.Stat(path)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
@@ -0,0 +1,112 @@
+package drawing
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// PSDrawer streams a single page PostScript document, or an EPS file
+// if the path ends with .eps. Coordinates are in dots from the top
+// left corner, and labels use the standard Courier font. The layers
+// and groups are ignored.
+type PSDrawer struct {
+	Dotter
+	layerStack
+	f io.WriteCloser
+	w *bufio.Writer
+}
+
+// mmToPoint converts mm to PostScript points.
+func mmToPoint(v float64) float64 {
+	return v * 72.0 / anInch
+}
+
+// psProlog defines the procedures drawing a filled rectangle, a line
+// and a circle, with the color last.
+const psProlog = `/F { setrgbcolor rectfill } bind def
+/L { setrgbcolor setlinewidth newpath moveto lineto stroke } bind def
+/C { setrgbcolor setlinewidth newpath 0 360 arc stroke } bind def
+`
+
+func NewPSDrawer(path string, width, height float64, DPI int) (Drawer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	res := &PSDrawer{
+		Dotter: Dotter{float64(DPI)},
+		f:      f,
+		w:      bufio.NewWriter(f),
+	}
+	w, h := mmToPoint(width), mmToPoint(height)
+	eps := filepath.Ext(path) == ".eps"
+	if eps == true {
+		res.printf("%%!PS-Adobe-3.0 EPSF-3.0\n")
+	} else {
+		res.printf("%%!PS-Adobe-3.0\n")
+	}
+	res.printf("%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(w)), int(math.Ceil(h)))
+	res.printf("%%%%HiResBoundingBox: 0 0 %s %s\n", formatNumber(w), formatNumber(h))
+	res.printf("%%%%Creator: tag-layouter\n%%%%Pages: 1\n%%%%EndComments\n")
+	res.printf("%%%%BeginProlog\n%s%%%%EndProlog\n", psProlog)
+	if eps == false {
+		res.printf("%%%%BeginSetup\n<< /PageSize [%s %s] >> setpagedevice\n%%%%EndSetup\n", formatNumber(w), formatNumber(h))
+	}
+	res.printf("%%%%Page: 1 1\ngsave\n")
+	// draws in dots from the top left corner
+	scale := 72.0 / res.dpi
+	res.printf("0 %s translate %s %s scale\n", formatNumber(h), formatNumber(scale), formatNumber(-scale))
+	return res, nil
+}
+
+func (d *PSDrawer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.w, format, args...)
+}
+
+func (d *PSDrawer) Close() error {
+	d.printf("grestore\nshowpage\n%%%%EOF\n")
+	if err := d.w.Flush(); err != nil {
+		d.f.Close()
+		return err
+	}
+	return d.f.Close()
+}
+
+func (d *PSDrawer) RotateTranslate(x, y int, angle float64) {
+	d.printf("gsave %d %d translate", x, y)
+	if angle != 0 {
+		d.printf(" %s rotate", formatNumber(angle))
+	}
+	d.printf("\n")
+}
+
+func (d *PSDrawer) EndRotateTranslate() {
+	d.printf("grestore\n")
+}
+
+func (d *PSDrawer) BeginGroup(label string, metadata map[string]string) {}
+func (d *PSDrawer) EndGroup()                                           {}
+
+func (d *PSDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	d.printf("%d %d %d %d %s F\n", x, y, w, h, formatColor(c))
+}
+
+func (d *PSDrawer) DrawLine(x1, y1, x2, y2, border int, c color.Color) {
+	d.printf("%d %d %d %d %d %s L\n", x2, y2, x1, y1, border, formatColor(c))
+}
+
+func (d *PSDrawer) DrawCircle(x, y, radius, border int, c color.Color) {
+	d.printf("%d %d %d %d %s C\n", x, y, radius, border, formatColor(c))
+}
+
+func (d *PSDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	// the font matrix flips the glyphs back
+	d.printf("%s setrgbcolor /Courier findfont [%d 0 0 %d 0 0] makefont setfont %d %d moveto %s show\n",
+		formatColor(c), height, -height, x, y+height, formatString(label))
+	return courierAdvance * float64(len(label)) * d.ToMM(height)
+}
@@ -0,0 +1,44 @@
+package drawing
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *VectorDrawerSuite) TestPostScript(c *C) {
+	dir := c.MkDir()
+	for _, ext := range []string{".ps", ".eps"} {
+		path := filepath.Join(dir, "scene"+ext)
+		d, err := NewPSDrawer(path, 20, 10, 254)
+		c.Assert(err, IsNil)
+		drawTestBlock(d)
+		c.Assert(d.Close(), IsNil)
+
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, IsNil)
+		content := string(data)
+		comment := Commentf("for %s", ext)
+		if ext == ".eps" {
+			c.Check(strings.HasPrefix(content, "%!PS-Adobe-3.0 EPSF-3.0\n"), Equals, true, comment)
+			c.Check(strings.Contains(content, "setpagedevice"), Equals, false, comment)
+		} else {
+			c.Check(strings.HasPrefix(content, "%!PS-Adobe-3.0\n"), Equals, true, comment)
+			c.Check(strings.Contains(content, "<< /PageSize [56.692913 28.346457] >> setpagedevice"), Equals, true, comment)
+		}
+		c.Check(strings.Contains(content, "\n%%BoundingBox: 0 0 57 29\n"), Equals, true, comment)
+		c.Check(strings.HasSuffix(content, "grestore\nshowpage\n%%EOF\n"), Equals, true, comment)
+
+		count := func(expr string) int {
+			return len(regexp.MustCompile(`(?m)`+expr).FindAllString(content, -1))
+		}
+		c.Check(count(`gsave`), Equals, count(`grestore`), comment)
+		c.Check(count(` F$`), Equals, 5, comment)
+		c.Check(count(` L$`), Equals, 1, comment)
+		c.Check(count(` C$`), Equals, 1, comment)
+		c.Check(count(`1 0 0 setrgbcolor /Courier findfont \[25 0 0 -25 0 0\] makefont setfont 0 25 moveto \(42 TAGS\) show$`), Equals, 1, comment)
+	}
+}
@@ -0,0 +1,332 @@
+package drawing
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// raster is the backing store of a rasterDrawer.
+type raster interface {
+	draw.Image
+	FillRect(r image.Rectangle, c color.Color)
+	// Row returns the encoded pixels of row y.
+	Row(y int) []byte
+}
+
+type grayRaster struct {
+	*image.Gray
+}
+
+func (g grayRaster) FillRect(r image.Rectangle, c color.Color) {
+	r = r.Intersect(g.Rect)
+	gc, _ := color.GrayModel.Convert(c).(color.Gray)
+	for j := r.Min.Y; j < r.Max.Y; j++ {
+		row := g.Pix[g.PixOffset(r.Min.X, j):g.PixOffset(r.Max.X, j)]
+		for i := range row {
+			row[i] = gc.Y
+		}
+	}
+}
+
+func (g grayRaster) Row(y int) []byte {
+	start := g.PixOffset(g.Rect.Min.X, y)
+	return g.Pix[start : start+g.Rect.Dx()]
+}
+
+// rgbRaster stores 3 bytes per pixel, or 4 with a non premultiplied
+// alpha.
+type rgbRaster struct {
+	Pix      []byte
+	Stride   int
+	Rect     image.Rectangle
+	channels int
+}
+
+func newRGBRaster(r image.Rectangle, channels int) *rgbRaster {
+	return &rgbRaster{
+		Pix:      make([]byte, channels*r.Dx()*r.Dy()),
+		Stride:   channels * r.Dx(),
+		Rect:     r,
+		channels: channels,
+	}
+}
+
+func (r *rgbRaster) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+func (r *rgbRaster) Bounds() image.Rectangle {
+	return r.Rect
+}
+
+func (r *rgbRaster) offset(x, y int) int {
+	return (y-r.Rect.Min.Y)*r.Stride + r.channels*(x-r.Rect.Min.X)
+}
+
+// pixel returns the stored bytes of c.
+func (r *rgbRaster) pixel(c color.Color) []byte {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return []byte{nrgba.R, nrgba.G, nrgba.B, nrgba.A}[:r.channels]
+}
+
+func (r *rgbRaster) At(x, y int) color.Color {
+	if (image.Point{x, y}).In(r.Rect) == false {
+		return color.NRGBA{}
+	}
+	i := r.offset(x, y)
+	res := color.NRGBA{r.Pix[i], r.Pix[i+1], r.Pix[i+2], 0xff}
+	if r.channels == 4 {
+		res.A = r.Pix[i+3]
+	}
+	return res
+}
+
+func (r *rgbRaster) Set(x, y int, c color.Color) {
+	if (image.Point{x, y}).In(r.Rect) == false {
+		return
+	}
+	copy(r.Pix[r.offset(x, y):], r.pixel(c))
+}
+
+func (r *rgbRaster) FillRect(rect image.Rectangle, c color.Color) {
+	rect = rect.Intersect(r.Rect)
+	if rect.Empty() {
+		return
+	}
+	pixel := r.pixel(c)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := r.Pix[r.offset(rect.Min.X, y):r.offset(rect.Max.X, y)]
+		for i := 0; i < len(row); i += r.channels {
+			copy(row[i:], pixel)
+		}
+	}
+}
+
+func (r *rgbRaster) Row(y int) []byte {
+	start := r.offset(r.Rect.Min.X, y)
+	return r.Pix[start : start+r.Stride]
+}
+
+// RasterMode is the pixel format of raster outputs.
+type RasterMode int
+
+const (
+	GrayMode RasterMode = iota
+	BilevelMode
+	RGBMode
+	// RGBAMode has a transparent background
+	RGBAMode
+)
+
+// newRaster returns a raster starting black, but for BilevelMode which
+// starts white and RGBAMode which starts transparent.
+func newRaster(r image.Rectangle, mode RasterMode) raster {
+	switch mode {
+	case BilevelMode:
+		return NewBilevel(r)
+	case RGBMode:
+		return newRGBRaster(r, 3)
+	case RGBAMode:
+		return newRGBRaster(r, 4)
+	}
+	return grayRaster{image.NewGray(r)}
+}
+
+func parseMonoFont() (*truetype.Font, error) {
+	return truetype.Parse(gomono.TTF)
+}
+
+// rasterDrawer draws in memory on a raster, which may only cover a
+// band of the page. The layers in separated are drawn on their own
+// raster.
+type rasterDrawer struct {
+	Dotter
+	layerStack
+	data      raster
+	separated map[Layer]raster
+
+	fd *freetype.Context
+	x  []int
+	y  []int
+}
+
+func newRasterDrawer(DPI float64, font *truetype.Font, data raster, separated map[Layer]raster) *rasterDrawer {
+	res := &rasterDrawer{
+		Dotter:    Dotter{DPI},
+		data:      data,
+		separated: separated,
+	}
+	res.fd = freetype.NewContext()
+	res.fd.SetDPI(DPI)
+	res.fd.SetFont(font)
+	return res
+}
+
+// target returns the raster of the current layer.
+func (d *rasterDrawer) target() raster {
+	if r, ok := d.separated[d.Layer()]; ok == true {
+		return r
+	}
+	return d.data
+}
+
+func (d *rasterDrawer) Close() error {
+	return nil
+}
+
+func (d *rasterDrawer) BeginGroup(label string, metadata map[string]string) {}
+func (d *rasterDrawer) EndGroup()                                           {}
+
+func (d *rasterDrawer) Offsets() (int, int) {
+	if len(d.x) == 0 {
+		return 0, 0
+	}
+	return d.x[len(d.x)-1], d.y[len(d.y)-1]
+}
+
+type Vec2 struct {
+	x, y float64
+}
+
+func (v *Vec2) Norm() float64 {
+	return math.Sqrt(v.x*v.x + v.y*v.y)
+}
+
+func (d *rasterDrawer) DrawCircle(x, y, r, hb int, c color.Color) {
+	xo, yo := d.Offsets()
+	for i := x - r - hb - 1; i <= x+r+hb+1; i++ {
+		for j := y - r - hb - 1; j <= y+r+hb+1; j++ {
+			dist := (&Vec2{float64(i - x), float64(j - y)}).Norm()
+			if dist < float64(r+hb) && dist > float64(r-hb) {
+				d.target().Set(i+xo, j+yo, c)
+			}
+		}
+	}
+
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
+
+func (d *rasterDrawer) DrawLine(x1, y1, x2, y2, b int, c color.Color) {
+	xo, yo := d.Offsets()
+	if abs(x1-x2) > (y1 - y2) {
+		if x1 > x2 {
+			x1, x2 = x2, x1
+			y1, y2 = y2, y1
+		}
+
+		for x := x1; x <= x2; x++ {
+			y := y1 + (y2-y1)*(x-x1)/(x2-x1)
+			d.target().Set(x+xo, y+yo, c)
+		}
+	} else {
+		if y1 > y2 {
+			x1, x2 = x2, x1
+			y1, y2 = y2, y1
+		}
+
+		for y := y1; y <= y2; y++ {
+			x := x1 + (x2-x1)*(y-y1)/(y2-y1)
+			d.target().Set(x+xo, y+yo, c)
+		}
+
+	}
+}
+
+func (d *rasterDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	xo, yo := d.Offsets()
+	d.target().FillRect(image.Rect(x+xo, y+yo, x+xo+w, y+yo+h), c)
+}
+
+func (d *rasterDrawer) RotateTranslate(x, y int, angle float64) {
+	if angle != 0 {
+		panic("angles are not supported")
+	}
+	xo, yo := d.Offsets()
+	d.x = append(d.x, x+xo)
+	d.y = append(d.y, y+yo)
+}
+
+func (d *rasterDrawer) EndRotateTranslate() {
+	if len(d.x) == 0 {
+		return
+	}
+	d.x = d.x[0:(len(d.x) - 1)]
+	d.y = d.y[0:(len(d.y) - 1)]
+}
+
+const (
+	PtInMM float64 = 0.352778
+)
+
+// labelCoverage is the minimal coverage of a pixel by a glyph for it
+// to be drawn, as labels are not anti-aliased.
+const labelCoverage = 0x38
+
+// textColor returns the color of text drawn with c on dst. Gray text
+// would be halftoned by printers, so on a raster without colors, it is
+// either black or white.
+func textColor(dst raster, c color.Color) color.Color {
+	if _, ok := dst.(*rgbRaster); ok == true {
+		return c
+	}
+	if color.GrayModel.Convert(c).(color.Gray).Y < 200 {
+		return color.Black
+	}
+	return color.White
+}
+
+func (d *rasterDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	xo, yo := d.Offsets()
+	x, y = x+xo, y+yo
+
+	size := d.ToMM(height) / PtInMM
+	d.fd.SetFontSize(size)
+	pt := freetype.Pt(x, y+int(d.fd.PointToFixed(size)>>6))
+
+	// glyphs are rasterized on a coverage mask, large enough for any
+	// monospaced label
+	dst := d.target()
+	bounds := image.Rect(x-height, y-height, x+(len(label)+2)*height, y+3*height).Intersect(dst.Bounds())
+	mask := image.NewAlpha(bounds)
+	d.fd.SetClip(bounds)
+	d.fd.SetDst(mask)
+	d.fd.SetSrc(image.Opaque)
+	advance, _ := d.fd.DrawString(label, pt)
+
+	ink := textColor(dst, c)
+	for j := bounds.Min.Y; j < bounds.Max.Y; j++ {
+		for i := bounds.Min.X; i < bounds.Max.X; i++ {
+			if mask.AlphaAt(i, j).A >= labelCoverage {
+				dst.Set(i, j, ink)
+			}
+		}
+	}
+
+	return d.ToMM(advance.X.Ceil() - pt.X.Ceil())
+}
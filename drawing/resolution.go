@@ -0,0 +1,44 @@
+package drawing
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+)
+
+// pngHeaderSize is the size of the PNG signature and IHDR chunk, which
+// must come first.
+const pngHeaderSize = 8 + 4 + 4 + 13 + 4
+
+// pngChunk returns a PNG chunk of type name holding data.
+func pngChunk(name string, data []byte) []byte {
+	res := make([]byte, 4+4+len(data)+4)
+	binary.BigEndian.PutUint32(res[0:], uint32(len(data)))
+	copy(res[4:], name)
+	copy(res[8:], data)
+	binary.BigEndian.PutUint32(res[8+len(data):], crc32.ChecksumIEEE(res[4:8+len(data)]))
+	return res
+}
+
+// pngPhysChunk returns a pHYs chunk storing DPI.
+func pngPhysChunk(DPI float64) []byte {
+	pixelsPerMeter := uint32(math.Round(DPI / anInch * 1000))
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data[0:], pixelsPerMeter)
+	binary.BigEndian.PutUint32(data[4:], pixelsPerMeter)
+	// unit is the meter
+	data[8] = 1
+	return pngChunk("pHYs", data)
+}
+
+const (
+	tiffXResolution = 282
+	tiffYResolution = 283
+	tiffRational    = 5
+)
+
+// tiffResolution returns DPI as a TIFF rational, the resolution unit
+// being the inch.
+func tiffResolution(DPI float64) [2]uint32 {
+	return [2]uint32{uint32(math.Round(DPI * 100)), 100}
+}
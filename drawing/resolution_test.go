@@ -0,0 +1,94 @@
+package drawing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/tiff"
+	. "gopkg.in/check.v1"
+)
+
+// subRaster returns the part of r within band, sharing its pixels.
+func subRaster(r raster, band image.Rectangle) raster {
+	if b, ok := r.(*Bilevel); ok == true {
+		start := (band.Min.Y - b.Rect.Min.Y) * b.Stride
+		return &Bilevel{Pix: b.Pix[start : start+band.Dy()*b.Stride], Stride: b.Stride, Rect: band}
+	}
+	return grayRaster{r.(grayRaster).SubImage(band).(*image.Gray)}
+}
+
+// encodeBands encodes r in bands of bandHeight rows with the encoder
+// of ext, and returns the encoded data.
+func encodeBands(c *C, ext string, r raster, DPI float64, mode RasterMode, bandHeight int) []byte {
+	path := filepath.Join(c.MkDir(), "image"+ext)
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	bounds := r.Bounds()
+	encoder, err := encoders[ext](f, bounds.Dx(), bounds.Dy(), DPI, mode)
+	c.Assert(err, IsNil)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += bandHeight {
+		band := image.Rect(bounds.Min.X, y, bounds.Max.X, min(y+bandHeight, bounds.Max.Y))
+		compressed, err := encoder.Compress(subRaster(r, band))
+		c.Assert(err, IsNil)
+		c.Assert(encoder.Write(band.Dy(), compressed), IsNil)
+	}
+	c.Assert(encoder.Close(), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	return data
+}
+
+type ResolutionSuite struct {
+	Image raster
+}
+
+var _ = Suite(&ResolutionSuite{})
+
+func (s *ResolutionSuite) SetUpSuite(c *C) {
+	s.Image = newRaster(image.Rect(0, 0, 13, 7), GrayMode)
+	s.Image.Set(3, 0, color.White)
+}
+
+func (s *ResolutionSuite) TestPNG(c *C) {
+	data := encodeBands(c, ".png", s.Image, 2400, GrayMode, 3)
+	c.Check(string(data[pngHeaderSize+4:pngHeaderSize+8]), Equals, "pHYs")
+	// 2400 DPI is 94488.19 pixels per meter
+	c.Check(binary.BigEndian.Uint32(data[pngHeaderSize+8:]), Equals, uint32(94488))
+	c.Check(binary.BigEndian.Uint32(data[pngHeaderSize+12:]), Equals, uint32(94488))
+	c.Check(data[pngHeaderSize+16], Equals, byte(1))
+
+	decoded, err := png.Decode(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Check(decoded.Bounds(), Equals, s.Image.Bounds())
+}
+
+func (s *ResolutionSuite) TestTIFF(c *C) {
+	data := encodeBands(c, ".tiff", s.Image, 1200, GrayMode, 3)
+
+	order := binary.LittleEndian
+	ifd := int(order.Uint32(data[4:]))
+	resolutions := map[uint16][2]uint32{}
+	for i := 0; i < int(order.Uint16(data[ifd:])); i++ {
+		entry := data[ifd+2+12*i:]
+		tag := order.Uint16(entry)
+		if tag == tiffXResolution || tag == tiffYResolution {
+			offset := order.Uint32(entry[8:])
+			resolutions[tag] = [2]uint32{order.Uint32(data[offset:]), order.Uint32(data[offset+4:])}
+		}
+	}
+	c.Check(resolutions, DeepEquals, map[uint16][2]uint32{
+		tiffXResolution: {120000, 100},
+		tiffYResolution: {120000, 100},
+	})
+
+	decoded, err := tiff.Decode(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Check(decoded.Bounds(), Equals, s.Image.Bounds())
+}
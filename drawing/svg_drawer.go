@@ -0,0 +1,161 @@
+package drawing
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	svg "github.com/ajstarks/svgo"
+)
+
+type toDotConverter func(float64) int
+
+// svgLayer holds the elements drawn on a layer, which are written in
+// an Inkscape layer on Close.
+type svgLayer struct {
+	buffer bytes.Buffer
+	SVG    *svg.SVG
+	// open is the number of groups of the drawer opened in the layer
+	open int
+}
+
+// SVGDrawer writes each layer as an Inkscape layer. The transformations
+// and groups are repeated in each layer they contain elements of, so a
+// layer can be hidden or locked without changing the other ones.
+type SVGDrawer struct {
+	Dotter
+	layerStack
+	f             io.WriteCloser
+	width, height int
+	// groups are the opening tags of the transformations and groups
+	// currently open
+	groups []string
+	layers map[Layer]*svgLayer
+}
+
+func NewSVGDrawer(filepath string, width, height float64, DPI int) (Drawer, error) {
+	f, err := os.Create(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SVGDrawer{
+		Dotter: Dotter{float64(DPI)},
+		f:      f,
+		layers: map[Layer]*svgLayer{},
+	}
+	res.width = res.ToDot(width)
+	res.height = res.ToDot(height)
+	return res, nil
+}
+
+func (d *SVGDrawer) Close() error {
+	out := svg.New(d.f)
+	out.Start(d.width, d.height, `xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"`)
+	for i := range layerNames {
+		l, ok := d.layers[Layer(i)]
+		if ok == false {
+			continue
+		}
+		out.Group(fmt.Sprintf(`id="layer-%s"`, Layer(i)), `inkscape:groupmode="layer"`, fmt.Sprintf(`inkscape:label="%s"`, Layer(i)))
+		if _, err := l.buffer.WriteTo(d.f); err != nil {
+			d.f.Close()
+			return err
+		}
+		out.Gend()
+	}
+	out.End()
+	return d.f.Close()
+}
+
+// svg returns the SVG of the current layer, where all the groups are
+// open.
+func (d *SVGDrawer) svg() *svg.SVG {
+	l, ok := d.layers[d.Layer()]
+	if ok == false {
+		l = &svgLayer{}
+		l.SVG = svg.New(&l.buffer)
+		d.layers[d.Layer()] = l
+	}
+	for ; l.open < len(d.groups); l.open++ {
+		l.buffer.WriteString(d.groups[l.open])
+	}
+	return l.SVG
+}
+
+func (d *SVGDrawer) beginGroup(attributes string) {
+	d.groups = append(d.groups, "<g "+attributes+">\n")
+}
+
+func (d *SVGDrawer) endGroup() {
+	if len(d.groups) == 0 {
+		return
+	}
+	d.groups = d.groups[:len(d.groups)-1]
+	for _, l := range d.layers {
+		if l.open > len(d.groups) {
+			l.SVG.Gend()
+			l.open--
+		}
+	}
+}
+
+func (d *SVGDrawer) RotateTranslate(x, y int, angle float64) {
+	radians := angle * math.Pi / 180.0
+	xCanvas := float64(x)*math.Cos(radians) + float64(y)*math.Sin(radians)
+	yCanvas := -float64(x)*math.Sin(radians) + float64(y)*math.Cos(radians)
+
+	d.beginGroup(fmt.Sprintf(`transform="rotate(%g) translate(%d,%d)"`, angle, int(xCanvas), int(yCanvas)))
+}
+
+func (d *SVGDrawer) EndRotateTranslate() {
+	d.endGroup()
+}
+
+// BeginGroup opens a group labeled in Inkscape, with the metadata as
+// data attributes.
+func (d *SVGDrawer) BeginGroup(label string, metadata map[string]string) {
+	attributes := fmt.Sprintf(`inkscape:label="%s"`, html.EscapeString(label))
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attributes += fmt.Sprintf(` data-%s="%s"`, k, html.EscapeString(metadata[k]))
+	}
+	d.beginGroup(attributes)
+}
+
+func (d *SVGDrawer) EndGroup() {
+	d.endGroup()
+}
+
+// svgColor returns c as a SVG color.
+func svgColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("rgb(%d,%d,%d)", r/256, g/256, b/256)
+}
+
+func (d *SVGDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	d.svg().Rect(x, y, w, h, fmt.Sprintf("stroke:none;fill:%s", svgColor(c)))
+}
+
+func (d *SVGDrawer) DrawLine(x1, y1, x2, y2, border int, c color.Color) {
+	d.svg().Line(x1, y1, x2, y2, fmt.Sprintf("stroke:%s;stroke-width:%d", svgColor(c), border))
+}
+
+func (d *SVGDrawer) Label(x, y int, height int, label string, c color.Color) float64 {
+	d.svg().Text(x, y+height, label, fmt.Sprintf("font-size:%d;font-family:Roboto;fill:%s", height, svgColor(c)))
+	return 0
+
+}
+
+func (d *SVGDrawer) DrawCircle(x, y, radius, border int, c color.Color) {
+	d.svg().Circle(x, y, radius, fmt.Sprintf("stroke:%s;fill:none;stroke-width:%d", svgColor(c), border))
+}
@@ -0,0 +1,88 @@
+package drawing
+
+import (
+	"encoding/xml"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type VectorDrawerSuite struct{}
+
+var _ = Suite(&VectorDrawerSuite{})
+
+type svgNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []svgNode  `xml:",any"`
+}
+
+func (n svgNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// count returns the number of elements named name within n.
+func (n svgNode) count(name string) int {
+	res := 0
+	for _, child := range n.Children {
+		if child.XMLName.Local == name {
+			res++
+		}
+		res += child.count(name)
+	}
+	return res
+}
+
+func drawTestBlock(d Drawer) {
+	d.BeginGroup("36H11 3.00MM 0-9", map[string]string{"family": "36H11", "ranges": "0-9"})
+	d.RotateTranslate(10, 5, 0)
+	drawTestScene(d)
+	d.EndRotateTranslate()
+	d.EndGroup()
+	d.BeginLayer(GuideLayer)
+	d.DrawRectangle(0, 0, 2, 2, color.Black)
+	d.EndLayer()
+}
+
+func (s *VectorDrawerSuite) TestSVGLayers(c *C) {
+	path := filepath.Join(c.MkDir(), "scene.svg")
+	d, err := NewSVGDrawer(path, 20, 10, 254)
+	c.Assert(err, IsNil)
+	drawTestBlock(d)
+	c.Assert(d.Close(), IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	root := svgNode{}
+	c.Assert(xml.Unmarshal(data, &root), IsNil)
+
+	layers := map[string]svgNode{}
+	for _, child := range root.Children {
+		c.Check(child.XMLName.Local, Equals, "g")
+		c.Check(child.attr("groupmode"), Equals, "layer")
+		layers[child.attr("label")] = child
+	}
+	c.Assert(len(layers), Equals, 4)
+	c.Check(layers["tags"].count("rect"), Equals, 3)
+	c.Check(layers["tags"].count("circle"), Equals, 1)
+	c.Check(layers["tags"].count("line"), Equals, 1)
+	c.Check(layers["cuts"].count("rect"), Equals, 1)
+	c.Check(layers["labels"].count("text"), Equals, 1)
+	c.Check(layers["guides"].count("rect"), Equals, 1)
+	c.Check(layers["guides"].count("g"), Equals, 0)
+
+	// the block group is repeated in each layer it has elements in
+	for _, name := range []string{"tags", "cuts", "labels"} {
+		block := layers[name].Children[0]
+		c.Check(block.attr("label"), Equals, "36H11 3.00MM 0-9", Commentf("in %s", name))
+		c.Check(block.attr("data-family"), Equals, "36H11", Commentf("in %s", name))
+		c.Check(block.attr("data-ranges"), Equals, "0-9", Commentf("in %s", name))
+	}
+}
@@ -1,12 +1,14 @@
-package main
+package drawing
 
 import (
 	"fmt"
 	"image/color"
 	"math"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
 )
 
-func drawTagDotPrivate(drawer Drawer, tf *TagFamily, payload uint64, size int) error {
+func drawTagDotPrivate(drawer Drawer, tf *families.TagFamily, payload uint64, size int) error {
 	pixelSize := size / tf.TotalWidth
 	colorOut := color.White
 	colorIn := color.Black
@@ -37,13 +39,13 @@ func drawTagDotPrivate(drawer Drawer, tf *TagFamily, payload uint64, size int) e
 	return nil
 }
 
-func DrawTagDot(drawer Drawer, tf *TagFamily, payload uint64, x, y, size int) error {
+func DrawTagDot(drawer Drawer, tf *families.TagFamily, payload uint64, x, y, size int) error {
 	drawer.RotateTranslate(x, y, 0.0)
 	defer drawer.EndRotateTranslate()
 	return drawTagDotPrivate(drawer, tf, payload, size)
 }
 
-func DrawTag(drawer Drawer, tf *TagFamily, payload uint64, x, y, size, angle float64, value *int) error {
+func DrawTag(drawer Drawer, tf *families.TagFamily, payload uint64, x, y, size, angle float64, value *int) error {
 
 	sizeInPX := tf.TotalWidth
 	pixelSize := drawer.ToDot(size / float64(sizeInPX))
@@ -0,0 +1,64 @@
+package drawing
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+)
+
+// TagImage draws a single tag, within a quiet zone and optionally
+// captioned with its ID below. Sizes are in dots.
+type TagImage struct {
+	// Module is the size of a bit of the tag
+	Module int
+	// QuietZone is the number of modules around the tag
+	QuietZone int
+	Caption   bool
+	// Transparent leaves the quiet zone and the caption background
+	// unpainted, rather than white
+	Transparent bool
+}
+
+// CaptionHeight returns the height of the caption text of tf.
+func (t TagImage) CaptionHeight(tf *families.TagFamily) int {
+	return max(8, t.Module*tf.TotalWidth/6)
+}
+
+// Size returns the size of the image of a tag of tf.
+func (t TagImage) Size(tf *families.TagFamily) (int, int) {
+	width := (tf.TotalWidth + 2*t.QuietZone) * t.Module
+	height := width
+	if t.Caption == true {
+		height += 2 * t.CaptionHeight(tf)
+	}
+	return width, height
+}
+
+// Draw draws the tag id of tf from the top left corner of drawer.
+func (t TagImage) Draw(drawer Drawer, tf *families.TagFamily, id int) error {
+	if t.Module <= 0 {
+		return fmt.Errorf("Module size must be strictly positive")
+	}
+	if id < 0 || id >= len(tf.Codes) {
+		return fmt.Errorf("%d is out of range for %s (%d codes)", id, tf.Name, len(tf.Codes))
+	}
+	width, height := t.Size(tf)
+	quiet := t.QuietZone * t.Module
+	if t.Transparent == false {
+		drawer.DrawRectangle(0, 0, width, height, color.White)
+	}
+	if err := DrawTagDot(drawer, tf, tf.Codes[id], quiet, quiet, tf.TotalWidth*t.Module); err != nil {
+		return err
+	}
+	if t.Caption == false {
+		return nil
+	}
+	label := fmt.Sprintf("%d", id)
+	h := t.CaptionHeight(tf)
+	x := (width - int(courierAdvance*float64(h*len(label)))) / 2
+	drawer.BeginLayer(LabelLayer)
+	drawer.Label(x, width+h/2, h, label, color.Black)
+	drawer.EndLayer()
+	return nil
+}
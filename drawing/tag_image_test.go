@@ -0,0 +1,62 @@
+package drawing
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	. "gopkg.in/check.v1"
+)
+
+type TagImageSuite struct {
+	Family *families.TagFamily
+}
+
+var _ = Suite(&TagImageSuite{})
+
+func (s *TagImageSuite) SetUpSuite(c *C) {
+	var err error
+	s.Family, err = families.GetFamily("36h11")
+	c.Assert(err, IsNil)
+}
+
+func (s *TagImageSuite) TestSize(c *C) {
+	image := TagImage{Module: 4}
+	width, height := image.Size(s.Family)
+	c.Check(width, Equals, 40)
+	c.Check(height, Equals, 40)
+
+	image = TagImage{Module: 4, QuietZone: 2, Caption: true}
+	width, height = image.Size(s.Family)
+	c.Check(width, Equals, 56)
+	c.Check(height, Equals, 56+2*8)
+
+	c.Check(TagImage{Module: 0}.Draw(NewMeasureDrawer(300), s.Family, 0), ErrorMatches, "Module size must be strictly positive")
+	c.Check(TagImage{Module: 1}.Draw(NewMeasureDrawer(300), s.Family, 587), ErrorMatches, "587 is out of range for 36H11 \\(587 codes\\)")
+}
+
+func (s *TagImageSuite) TestTransparentQuietZone(c *C) {
+	// at 254 DPI, a dot is 0.1mm
+	path := filepath.Join(c.MkDir(), "tag.png")
+	image := TagImage{Module: 2, QuietZone: 1, Transparent: true}
+	drawer, err := NewImageDrawer(path, 2.4, 2.4, 254, ImageOptions{Mode: RGBAMode})
+	c.Assert(err, IsNil)
+	c.Assert(image.Draw(drawer, s.Family, 0), IsNil)
+	c.Assert(drawer.Close(), IsNil)
+
+	f, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	c.Assert(err, IsNil)
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+	}
+	// the quiet zone, the white and the black borders of the tag
+	c.Check(at(0, 0).A, Equals, uint8(0))
+	c.Check(at(23, 12).A, Equals, uint8(0))
+	c.Check(at(2, 2), Equals, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	c.Check(at(4, 12), Equals, color.NRGBA{0x00, 0x00, 0x00, 0xff})
+}
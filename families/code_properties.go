@@ -0,0 +1,141 @@
+package families
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func (tf *TagFamily) bitMask(i int) uint64 {
+	return uint64(1) << uint64(tf.NBits-1-i)
+}
+
+// rotationPermutation returns for each bit the index of the bit it
+// lands on when the tag is rotated by 90 degrees clockwise.
+func (tf *TagFamily) rotationPermutation() ([]int, error) {
+	offset := (tf.TotalWidth - tf.WidthAtBorder) / 2
+	locations := make(map[image.Point]int, tf.NBits)
+	for i := 0; i < tf.NBits; i++ {
+		locations[image.Point{offset + tf.LocationX[i], offset + tf.LocationY[i]}] = i
+	}
+
+	res := make([]int, tf.NBits)
+	for i := 0; i < tf.NBits; i++ {
+		x := offset + tf.LocationX[i]
+		y := offset + tf.LocationY[i]
+		j, ok := locations[image.Point{tf.TotalWidth - 1 - y, x}]
+		if ok == false {
+			return nil, fmt.Errorf("family %s is not invariant by rotation: bit %d has no rotated counterpart", tf.Name, i)
+		}
+		res[i] = j
+	}
+	return res, nil
+}
+
+// CodeRotator rotates the codes of a family, with the bit permutation
+// computed once.
+type CodeRotator struct {
+	tf   *TagFamily
+	perm []int
+}
+
+// NewCodeRotator fails if the family is not invariant by rotation.
+func (tf *TagFamily) NewCodeRotator() (*CodeRotator, error) {
+	perm, err := tf.rotationPermutation()
+	if err != nil {
+		return nil, err
+	}
+	return &CodeRotator{tf: tf, perm: perm}, nil
+}
+
+// Rotate90 returns code rotated by 90 degrees clockwise.
+func (r *CodeRotator) Rotate90(code uint64) uint64 {
+	res := uint64(0)
+	for i, j := range r.perm {
+		if code&r.tf.bitMask(i) != 0 {
+			res |= r.tf.bitMask(j)
+		}
+	}
+	return res
+}
+
+func (r *CodeRotator) rotations(code uint64) [4]uint64 {
+	res := [4]uint64{code}
+	for i := 1; i < 4; i++ {
+		res[i] = r.Rotate90(res[i-1])
+	}
+	return res
+}
+
+// MinRotationDistance returns the minimal Hamming distance between a
+// code and its three rotations.
+func (r *CodeRotator) MinRotationDistance(code uint64) int {
+	rotations := r.rotations(code)
+	res := r.tf.NBits
+	for _, rotated := range rotations[1:] {
+		if d := HammingDistance(code, rotated); d < res {
+			res = d
+		}
+	}
+	return res
+}
+
+// Distance returns the minimal Hamming distance between a and any
+// rotation of b.
+func (r *CodeRotator) Distance(a, b uint64) int {
+	res := r.tf.NBits
+	for _, rotated := range r.rotations(b) {
+		if d := HammingDistance(a, rotated); d < res {
+			res = d
+		}
+	}
+	return res
+}
+
+func (tf *TagFamily) Rotate90(code uint64) (uint64, error) {
+	r, err := tf.NewCodeRotator()
+	if err != nil {
+		return 0, err
+	}
+	return r.Rotate90(code), nil
+}
+
+// MinRotationDistance returns the minimal Hamming distance between a
+// code and its three rotations. A CodeRotator avoids computing the
+// rotation for each code.
+func (tf *TagFamily) MinRotationDistance(code uint64) (int, error) {
+	r, err := tf.NewCodeRotator()
+	if err != nil {
+		return 0, err
+	}
+	return r.MinRotationDistance(code), nil
+}
+
+// Distance returns the minimal Hamming distance between a and any
+// rotation of b. A CodeRotator avoids computing the rotation for each
+// pair of codes.
+func (tf *TagFamily) Distance(a, b uint64) (int, error) {
+	r, err := tf.NewCodeRotator()
+	if err != nil {
+		return 0, err
+	}
+	return r.Distance(a, b), nil
+}
+
+// BitBalance returns the absolute difference between the number of
+// set and unset bits of a code. 0 means a perfectly balanced code.
+func (tf *TagFamily) BitBalance(code uint64) int {
+	ones := bits.OnesCount64(code & (tf.bitMask(0)<<1 - 1))
+	return abs(2*ones - tf.NBits)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
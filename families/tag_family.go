@@ -1,6 +1,9 @@
-package main
+// Package families provides the apriltag families and the properties
+// of their codes.
+package families
 
 /*
+#cgo CFLAGS: -I${SRCDIR}/..
 #include "apriltag/apriltag.h"
 #include "apriltag/tag16h5.h"
 #include "apriltag/tag25h9.h"
@@ -11,7 +14,7 @@ package main
 #include "apriltag/tagStandard41h12.h"
 #include "apriltag/tagStandard52h13.h"
 #include "oldtags/tag36h10.h"
-#cgo LDFLAGS:  apriltag/libapriltag.a oldtags/liboldtags.a -lm
+#cgo LDFLAGS: ${SRCDIR}/../apriltag/libapriltag.a ${SRCDIR}/../oldtags/liboldtags.a -lm
 */
 import "C"
 import (
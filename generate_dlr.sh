@@ -32,7 +32,7 @@ build_family_opts() {
 	do
 		for s in $sizes_queen
 		do
-			local families_opts="$families_opts -t \"$f:$s:0;0;0;0\""
+			local families_opts="$families_opts -t \"$f:$s:0x4\""
 		done
 	done
 	echo $families_opts
@@ -63,10 +63,6 @@ layout_one() {
 						   --dpi $r \
 						   --paper-border 5 \
 						   $families_opts
-			if [ $f == "tiff" ]
-			then
-				convert ${file}_$r.$f -units PixelsPerInch -density $r ${file}_$r.$f
-			fi
 		done
 	done
 }
@@ -33,7 +33,7 @@ for f in $families
 do
 	for s in $sizes_queen
 	do
-		families_opts="$families_opts -t \"$f:$s:0;0;0;0\""
+		families_opts="$families_opts -t \"$f:$s:0x4\""
 	done
 done
 
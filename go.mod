@@ -1,4 +1,4 @@
-module gihtub.com/formicidae-tracker/tag-layouter
+module github.com/formicidae-tracker/tag-layouter
 
 go 1.14
 
@@ -9,4 +9,5 @@ require (
 	github.com/kr/pretty v0.1.0 // indirect
 	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127
+	rsc.io/qr v0.2.0
 )
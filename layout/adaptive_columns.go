@@ -0,0 +1,150 @@
+package layout
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+func argMinMax(values []int) (int, int) {
+	iMin, iMax := 0, 0
+	for i, v := range values {
+		if v < values[iMin] {
+			iMin = i
+		}
+		if v > values[iMax] {
+			iMax = i
+		}
+	}
+	return iMin, iMax
+}
+
+// packAdaptiveColumns stacks the families assigned to each column in
+// a column of the given width. It returns the height used by each
+// column, math.MaxInt32 for the ones that do not fit.
+func (c *ColumnLayouter) packAdaptiveColumns(families []FamilyBlock, assignment [][]int, widths []int, bounds image.Rectangle, margin int) ([]PlacedFamily, []int, error) {
+	res := []PlacedFamily{}
+	heights := make([]int, len(widths))
+	var firstErr error
+	x := bounds.Min.X
+	for col, indexes := range assignment {
+		column := image.Rect(x, bounds.Min.Y, x+widths[col], bounds.Max.Y)
+		x += widths[col] + margin
+
+		shapes := make([][]PlacedFamily, 0, len(indexes))
+		for _, i := range indexes {
+			shapes = append(shapes, []PlacedFamily{c.ComputeFamilySize(families[i], widths[col])})
+		}
+		placed, err := (&ColumnPacker{NColumns: 1}).Pack(shapes, column, margin)
+		if err != nil {
+			heights[col] = math.MaxInt32
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		heights[col] = UsedHeight(placed, column)
+		res = append(res, placed...)
+	}
+	return res, heights, firstErr
+}
+
+// placeInAdaptiveColumns distributes the families in nColumns columns
+// balancing their area, sizes each column to its share of the total
+// area, then moves width from the shortest to the tallest column as
+// long as it reduces the height used.
+func (c *ColumnLayouter) placeInAdaptiveColumns(families []FamilyBlock, bounds image.Rectangle, margin, nColumns int) ([]PlacedFamily, error) {
+	if c.Packing != "" && c.Packing != "column" {
+		return nil, fmt.Errorf("Adaptive column widths are only supported by the column packing")
+	}
+	if c.ReflowBlocks == true {
+		return nil, fmt.Errorf("Adaptive column widths cannot reflow family blocks")
+	}
+	usable := bounds.Dx() - margin*(nColumns-1)
+	if usable < nColumns {
+		return nil, fmt.Errorf("Invalid number of column")
+	}
+
+	// a family needs at least its label and a tag on its first row
+	areas := make([]float64, len(families))
+	minWidths := make([]int, len(families))
+	order := make([]int, len(families))
+	for i, f := range families {
+		pf := c.ComputeFamilySize(f, usable)
+		if pf.NTagsPerRow < 1 {
+			return nil, fmt.Errorf("Column is too narrow for %s:%.2f", f.Family.Name, f.Size)
+		}
+		pitch := float64(pf.ActualTagWidth + pf.ActualBorderWidth)
+		areas[i] = float64(pf.NumberOfTags()+pf.Skips) * pitch * pitch
+		minWidths[i] = (pf.Skips+1)*(pf.ActualTagWidth+pf.ActualBorderWidth) + pf.ActualBorderWidth
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return areas[order[i]] > areas[order[j]] })
+
+	assignment := make([][]int, nColumns)
+	loads := make([]float64, nColumns)
+	total := 0.0
+	for _, i := range order {
+		col := 0
+		for j := range loads {
+			if loads[j] < loads[col] {
+				col = j
+			}
+		}
+		assignment[col] = append(assignment[col], i)
+		loads[col] += areas[i]
+		total += areas[i]
+	}
+
+	if total == 0 {
+		total = 1
+	}
+
+	columnMin := make([]int, nColumns)
+	widths := make([]int, nColumns)
+	sum := 0
+	for col, indexes := range assignment {
+		for _, i := range indexes {
+			columnMin[col] = max(columnMin[col], minWidths[i])
+		}
+		widths[col] = max(columnMin[col], int(float64(usable)*loads[col]/total))
+		sum += widths[col]
+	}
+	for col := range widths {
+		if sum <= usable {
+			break
+		}
+		taken := min(sum-usable, widths[col]-columnMin[col])
+		widths[col] -= taken
+		sum -= taken
+	}
+	if sum > usable {
+		return nil, fmt.Errorf("Could not fit the families in %d columns", nColumns)
+	}
+	widths[nColumns-1] += usable - sum
+
+	placed, heights, err := c.packAdaptiveColumns(families, assignment, widths, bounds, margin)
+	step := usable / (4 * nColumns)
+	for iter := 0; iter < 64 && step > 0; iter++ {
+		shortest, tallest := argMinMax(heights)
+		if shortest == tallest || widths[shortest]-step < columnMin[shortest] {
+			step /= 2
+			continue
+		}
+		candidate := append([]int{}, widths...)
+		candidate[shortest] -= step
+		candidate[tallest] += step
+		cPlaced, cHeights, cErr := c.packAdaptiveColumns(families, assignment, candidate, bounds, margin)
+		_, cTallest := argMinMax(cHeights)
+		if cHeights[cTallest] < heights[tallest] {
+			placed, heights, err, widths = cPlaced, cHeights, cErr, candidate
+		} else {
+			step /= 2
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return placed, nil
+}
@@ -0,0 +1,81 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+// point is a tag center in mm.
+type point struct {
+	X, Y float64
+}
+
+// touches returns true if toTest is closer than radius to any of points.
+func touches(points map[int]point, toTest point, radius float64) bool {
+	for _, p := range points {
+		distX := p.X - toTest.X
+		distY := p.Y - toTest.Y
+		dist := math.Sqrt(distX*distX + distY*distY)
+		if dist < radius {
+			return true
+		}
+	}
+	return false
+}
+
+// ArenaLayouter places Number tags at random in the safe area of the
+// Page, framed in gray within the margins.
+type ArenaLayouter struct {
+	Page   Page
+	Number int
+}
+
+func (l *ArenaLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	if len(families) != 1 {
+		return fmt.Errorf("Arena layouter only supports a single family (got:%d)", len(families))
+	}
+	set := map[int]point{}
+
+	if err := l.Page.Check(); err != nil {
+		return err
+	}
+	m := l.Page.Margins
+	safeX, safeY, safeWidth, safeHeight := l.Page.SafeArea()
+
+	if m != (Margins{}) {
+		drawer.DrawRectangle(drawer.ToDot(m.Left/2), drawer.ToDot(m.Top/2), drawer.ToDot(l.Page.Width-(m.Left+m.Right)/2), drawer.ToDot(l.Page.Height-(m.Top+m.Bottom)/2), color.Gray{Y: 200})
+		drawer.DrawRectangle(drawer.ToDot(safeX), drawer.ToDot(safeY), drawer.ToDot(safeWidth), drawer.ToDot(safeHeight), color.White)
+	}
+
+	for i := 0; i < l.Number; i++ {
+		angle := rand.Float64() * 360.0
+		idx := 0
+		for {
+			idx = rand.Intn(len(families[0].Family.Codes) - 1)
+			if _, ok := set[idx]; ok == true {
+				continue
+			}
+			break
+		}
+		x := 0.0
+		y := 0.0
+
+		for {
+			x = rand.Float64()*(safeWidth-2*families[0].Size) + safeX + families[0].Size
+			y = rand.Float64()*(safeHeight-2*families[0].Size) + safeY + families[0].Size
+			p := point{x, y}
+			if touches(set, p, families[0].Size*3) == true {
+				continue
+			}
+			set[idx] = p
+			break
+		}
+
+		drawing.DrawTag(drawer, families[0].Family, families[0].Family.Codes[i], x, y, families[0].Size, angle, &i)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+// AtlasLayouter lays the families side by side, each as a grid of
+// Columns x Rows tags captioned with their ID, to inspect them on a
+// screen. Each page shows the next Columns x Rows tags of every
+// family. Sizes are in dots, as the atlas is drawn for a screen rather
+// than printed.
+type AtlasLayouter struct {
+	// Module is the size of a bit of the tags
+	Module  int
+	Columns int
+	Rows    int
+}
+
+// tagImage returns how a tag is drawn in a cell of the grid.
+func (a *AtlasLayouter) tagImage() drawing.TagImage {
+	return drawing.TagImage{Module: a.Module, QuietZone: 1, Caption: true}
+}
+
+func (a *AtlasLayouter) check() error {
+	if a.Module <= 0 {
+		return fmt.Errorf("Module size must be strictly positive")
+	}
+	if a.Columns <= 0 || a.Rows <= 0 {
+		return fmt.Errorf("Atlas needs at least one column and one row (got:%dx%d)", a.Columns, a.Rows)
+	}
+	return nil
+}
+
+// margin returns the space around and between the families.
+func (a *AtlasLayouter) margin() int {
+	return max(8, 4*a.Module)
+}
+
+// headerHeight returns the height of the family labels.
+func (a *AtlasLayouter) headerHeight(families []FamilyBlock) int {
+	res := 0
+	for _, f := range families {
+		res = max(res, 2*a.tagImage().CaptionHeight(f.Family))
+	}
+	return res
+}
+
+// Pages returns the number of pages needed to show all the tags.
+func (a *AtlasLayouter) Pages(families []FamilyBlock) int {
+	perPage := a.Columns * a.Rows
+	res := 0
+	for _, f := range families {
+		res = max(res, (f.NumberOfTags()+perPage-1)/perPage)
+	}
+	return res
+}
+
+// Size returns the size in dots of a page.
+func (a *AtlasLayouter) Size(families []FamilyBlock) (int, int, error) {
+	if err := a.check(); err != nil {
+		return 0, 0, err
+	}
+	if len(families) == 0 {
+		return 0, 0, fmt.Errorf("No family to lay out")
+	}
+	margin := a.margin()
+	width, height := margin, 0
+	for _, f := range families {
+		cellWidth, cellHeight := a.tagImage().Size(f.Family)
+		width += a.Columns*cellWidth + margin
+		height = max(height, a.Rows*cellHeight)
+	}
+	return width, 2*margin + a.headerHeight(families) + height, nil
+}
+
+// Layout draws the first page.
+func (a *AtlasLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	return a.LayoutPage(drawer, families, 0)
+}
+
+// LayoutPage draws the page of index page, starting at 0.
+func (a *AtlasLayouter) LayoutPage(drawer drawing.Drawer, families []FamilyBlock, page int) error {
+	width, height, err := a.Size(families)
+	if err != nil {
+		return err
+	}
+	drawer.DrawRectangle(0, 0, width, height, color.White)
+
+	image := a.tagImage()
+	margin := a.margin()
+	header := a.headerHeight(families)
+	perPage := a.Columns * a.Rows
+	x := margin
+	for _, f := range families {
+		cellWidth, cellHeight := image.Size(f.Family)
+		ids := ranges.IDsOfRanges(f.Ranges)
+		start := min(page*perPage, len(ids))
+		ids = ids[start:min(start+perPage, len(ids))]
+
+		label := f.Family.Name
+		if len(ids) > 0 {
+			label = fmt.Sprintf("%s %d-%d", label, ids[0], ids[len(ids)-1])
+		}
+		drawer.BeginLayer(drawing.LabelLayer)
+		drawer.Label(x, margin, header/2, label, color.Black)
+		drawer.EndLayer()
+
+		for i, id := range ids {
+			drawer.RotateTranslate(x+(i%a.Columns)*cellWidth, margin+header+(i/a.Columns)*cellHeight, 0.0)
+			err := image.Draw(drawer, f.Family, id)
+			drawer.EndRotateTranslate()
+			if err != nil {
+				return err
+			}
+		}
+		x += a.Columns*cellWidth + margin
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package layout
+
+import (
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type AtlasLayouterSuite struct {
+	Family *families.TagFamily
+}
+
+var _ = Suite(&AtlasLayouterSuite{})
+
+func (s *AtlasLayouterSuite) SetUpSuite(c *C) {
+	var err error
+	s.Family, err = families.GetFamily("36h11")
+	c.Assert(err, IsNil)
+}
+
+func (s *AtlasLayouterSuite) TestSizeAndPages(c *C) {
+	atlas := &AtlasLayouter{Module: 4, Columns: 10, Rows: 5}
+	blocks := []FamilyBlock{
+		{Family: s.Family, Ranges: []ranges.Range{{Begin: 0, End: 587}}},
+		{Family: s.Family, Ranges: []ranges.Range{{Begin: 0, End: 50}}},
+	}
+	width, height, err := atlas.Size(blocks)
+	c.Assert(err, IsNil)
+	// cells of 12 modules and a caption of 2*8 dots, within margins
+	// of 16 dots
+	c.Check(width, Equals, 16+2*(10*48+16))
+	c.Check(height, Equals, 2*16+16+5*(48+16))
+	c.Check(atlas.Pages(blocks), Equals, 12)
+
+	_, _, err = (&AtlasLayouter{Module: 4}).Size(blocks)
+	c.Check(err, ErrorMatches, "Atlas needs at least one column and one row \\(got:0x0\\)")
+	_, _, err = atlas.Size(nil)
+	c.Check(err, ErrorMatches, "No family to lay out")
+}
+
+func (s *AtlasLayouterSuite) TestLayoutsPages(c *C) {
+	atlas := &AtlasLayouter{Module: 1, Columns: 2, Rows: 2}
+	blocks := []FamilyBlock{
+		{Family: s.Family, Ranges: []ranges.Range{{Begin: 0, End: 10}}},
+		{Family: s.Family, Ranges: []ranges.Range{{Begin: 100, End: 106}}},
+	}
+	c.Assert(atlas.Pages(blocks), Equals, 3)
+	testdata := []struct {
+		Page   int
+		Labels []string
+	}{
+		{2, []string{"36H11 8-9", "8", "9", "36H11"}},
+		{1, []string{"36H11 4-7", "4", "5", "6", "7", "36H11 104-105", "104", "105"}},
+		{0, []string{"36H11 0-3", "0", "1", "2", "3", "36H11 100-103", "100", "101", "102", "103"}},
+	}
+	for _, d := range testdata {
+		drawer := newRecordingDrawer(96)
+		c.Assert(atlas.LayoutPage(drawer, blocks, d.Page), IsNil)
+		c.Check(drawer.Labels, DeepEquals, d.Labels, Commentf("page: %d", d.Page))
+	}
+
+	drawer := newRecordingDrawer(96)
+	c.Assert(atlas.Layout(drawer, blocks), IsNil)
+	c.Check(drawer.Labels, DeepEquals, testdata[2].Labels)
+}
@@ -0,0 +1,350 @@
+package layout
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+type ColumnLayouter struct {
+	Page             Page
+	NColumns         int
+	FamilyMargin     float64
+	TagBorder        float64
+	CutLine          float64
+	LabelroundedSize bool
+	Packing          string
+	ReflowBlocks     bool
+	// AutoColumns chooses the number of columns, up to NColumns
+	AutoColumns         bool
+	AdaptiveColumnWidth bool
+	drawer              drawing.Drawer
+}
+
+func (c *ColumnLayouter) PerfectPixelSizeMM(size float64, border float64, cutline float64, totalWidth int) (tagSizeDot int, borderSizeDot int, cutLineSizeDot int) {
+	perfectPixelSize := c.drawer.ToDot(size / float64(totalWidth))
+	//check if a little bit bigger is not better
+	errors := []float64{
+		math.Abs(c.drawer.ToMM(perfectPixelSize*totalWidth) - size),
+		math.Abs(c.drawer.ToMM((perfectPixelSize+1)*totalWidth) - size),
+	}
+	if errors[1] < errors[0] {
+		perfectPixelSize += 1
+	}
+
+	tagSizeDot = perfectPixelSize * totalWidth
+	borderSizeDot = int(math.Round(float64(tagSizeDot) * border))
+
+	cutLineSizeDot = int(math.Round(float64(borderSizeDot) * cutline))
+	if cutline != 0.0 {
+		if cutLineSizeDot == 0 {
+			cutLineSizeDot = 1
+		}
+		if (borderSizeDot-cutLineSizeDot)%2 == 1 {
+			borderSizeDot += 1
+		}
+	}
+	//	log.Printf("pixel: %d; tag: %d, border: %d, cut %d", perfectPixelSize, perfectPixelSize*totalWidth, borderSizeDot, cutLineSizeDot)
+
+	return tagSizeDot, borderSizeDot, cutLineSizeDot
+}
+
+type PlacedFamily struct {
+	FamilyBlock
+	Height int
+	Width  int
+	X      int
+	Y      int
+
+	ActualTagWidth    int
+	ActualBorderWidth int
+	CutLineWidth      int
+	NTagsPerRow       int
+	Skips             int
+}
+
+func (c *ColumnLayouter) ComputeFamilySize(f FamilyBlock, columnWidthDot int) PlacedFamily {
+	actualTagWidth, actualBorderWidth, cutlineWidth := c.PerfectPixelSizeMM(f.Size, c.TagBorder, c.CutLine, f.Family.TotalWidth)
+	skips := len(f.FamilyLabel())*1/2 + 1
+	nbSlots := f.NumberOfTags() + skips
+
+	nbTagsPerRow := (columnWidthDot - actualBorderWidth) / (actualTagWidth + actualBorderWidth)
+	if nbTagsPerRow < 1 {
+		return PlacedFamily{FamilyBlock: f, NTagsPerRow: nbTagsPerRow}
+	}
+	nbRows := nbSlots / nbTagsPerRow
+	if nbSlots%nbTagsPerRow != 0 {
+		nbRows += 1
+	}
+
+	height := nbRows*(actualTagWidth+actualBorderWidth) + actualBorderWidth
+	width := 0
+	if nbRows > 1 {
+		width = columnWidthDot
+	} else {
+		width = nbSlots*(actualTagWidth+actualBorderWidth) + actualBorderWidth
+	}
+
+	return PlacedFamily{
+		FamilyBlock:       f,
+		Height:            height,
+		Width:             width,
+		X:                 0,
+		Y:                 0,
+		ActualTagWidth:    actualTagWidth,
+		ActualBorderWidth: actualBorderWidth,
+		CutLineWidth:      cutlineWidth,
+		NTagsPerRow:       nbTagsPerRow,
+		Skips:             skips,
+	}
+}
+
+type PlacedFamilyListByHeight []PlacedFamily
+type PlacedFamilyListByWidth []PlacedFamily
+
+func (fhs PlacedFamilyListByHeight) Len() int {
+	return len(fhs)
+}
+
+func (fhs PlacedFamilyListByWidth) Len() int {
+	return len(fhs)
+}
+
+func (fhs PlacedFamilyListByHeight) Less(i, j int) bool {
+	return fhs[i].Height < fhs[j].Height
+}
+
+func (fhs PlacedFamilyListByWidth) Less(i, j int) bool {
+	return fhs[i].Width < fhs[j].Width
+}
+
+func (fhs PlacedFamilyListByHeight) Swap(i, j int) {
+	fhs[i], fhs[j] = fhs[j], fhs[i]
+}
+
+func (fhs PlacedFamilyListByWidth) Swap(i, j int) {
+	fhs[i], fhs[j] = fhs[j], fhs[i]
+}
+
+func (c *ColumnLayouter) LayoutOne(pf PlacedFamily) {
+	actualSizeMM := c.drawer.ToMM(pf.ActualTagWidth)
+	label := ""
+	if c.LabelroundedSize == true {
+		label = pf.FamilyLabelActualSize(actualSizeMM)
+	} else {
+		label = pf.FamilyLabel()
+	}
+
+	log.Printf("%s:%.2fmm:%s (%d tags) actual size: %.2f; error: %.2f%% at (%d,%d)",
+		pf.Family.Name,
+		pf.Size,
+		pf.RangeString(),
+		pf.NumberOfTags(),
+		actualSizeMM,
+		math.Abs(actualSizeMM-pf.Size)/pf.Size*100,
+		pf.X,
+		pf.Y)
+
+	c.drawer.BeginGroup(pf.FamilyLabel()+" "+pf.RangeString(), pf.Metadata(actualSizeMM))
+	defer c.drawer.EndGroup()
+
+	ix := pf.Skips % pf.NTagsPerRow
+	iy := pf.Skips / pf.NTagsPerRow
+
+	cutLinePos := (pf.ActualBorderWidth - pf.CutLineWidth) / 2
+	isFirst := true
+	for _, i := range ranges.IDsOfRanges(pf.Ranges) {
+		x := ix*(pf.ActualTagWidth+pf.ActualBorderWidth) + pf.X + pf.ActualBorderWidth
+		y := iy*(pf.ActualTagWidth+pf.ActualBorderWidth) + pf.Y + pf.ActualBorderWidth
+		drawing.DrawTagDot(c.drawer, pf.Family, pf.Family.Codes[i], x, y, pf.ActualTagWidth)
+		ix += 1
+		if ix >= pf.NTagsPerRow {
+			ix = 0
+			iy += 1
+		}
+		if pf.CutLineWidth == 0 {
+			continue
+		}
+
+		c.drawer.BeginLayer(drawing.CutLayer)
+		c.drawer.DrawRectangle(x+pf.ActualTagWidth+cutLinePos,
+			y,
+			pf.CutLineWidth,
+			pf.ActualTagWidth,
+			color.Black)
+
+		c.drawer.DrawRectangle(x,
+			y+pf.ActualTagWidth+cutLinePos,
+			pf.ActualTagWidth,
+			pf.CutLineWidth,
+			color.Black)
+
+		if ix == 1 || isFirst == true {
+			isFirst = false
+			c.drawer.DrawRectangle(x-pf.CutLineWidth-cutLinePos,
+				y,
+				pf.CutLineWidth,
+				pf.ActualTagWidth,
+				color.Black)
+		}
+
+		if iy == 0 || iy == 1 && ix <= pf.Skips {
+			c.drawer.DrawRectangle(x,
+				y-cutLinePos-pf.CutLineWidth,
+				pf.ActualTagWidth,
+				pf.CutLineWidth,
+				color.Black)
+		}
+		c.drawer.EndLayer()
+	}
+	c.drawer.BeginLayer(drawing.LabelLayer)
+	c.drawer.Label(pf.X+pf.ActualBorderWidth, pf.Y, pf.ActualTagWidth, label, color.RGBA{0xff, 00, 00, 0xff})
+	c.drawer.EndLayer()
+
+}
+
+func max(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// reflowedShape returns the family laid out in a block whose width is
+// close to the height of pf. The tags keep their orientation.
+func (c *ColumnLayouter) reflowedShape(pf PlacedFamily, maxWidth int) (PlacedFamily, bool) {
+	width := pf.Height
+	if width > maxWidth || width == pf.Width {
+		return pf, false
+	}
+	res := c.ComputeFamilySize(pf.FamilyBlock, width)
+	// the label needs to fit on the first row
+	if res.NTagsPerRow <= res.Skips {
+		return pf, false
+	}
+	res.Width = min(res.Width, res.NTagsPerRow*(res.ActualTagWidth+res.ActualBorderWidth)+res.ActualBorderWidth)
+	return res, true
+}
+
+// placeInColumns places the families in nColumns columns, of equal
+// width unless AdaptiveColumnWidth is set.
+func (c *ColumnLayouter) placeInColumns(families []FamilyBlock, bounds image.Rectangle, margin, nColumns int) ([]PlacedFamily, error) {
+	if c.AdaptiveColumnWidth == true {
+		return c.placeInAdaptiveColumns(families, bounds, margin, nColumns)
+	}
+	packer, err := NewPacker(c.Packing, nColumns)
+	if err != nil {
+		return nil, err
+	}
+	columnWidthDot := (bounds.Dx() - margin*(nColumns-1)) / nColumns
+
+	shapes := make([][]PlacedFamily, 0, len(families))
+	for _, f := range families {
+		pf := c.ComputeFamilySize(f, columnWidthDot)
+		if pf.NTagsPerRow < 1 {
+			return nil, fmt.Errorf("Column is too narrow for %s:%.2f", f.Family.Name, f.Size)
+		}
+		alternatives := []PlacedFamily{pf}
+		if c.ReflowBlocks == true {
+			if reflowed, ok := c.reflowedShape(pf, bounds.Dx()); ok == true {
+				alternatives = append(alternatives, reflowed)
+			}
+		}
+		shapes = append(shapes, alternatives)
+	}
+
+	return packer.Pack(shapes, bounds, margin)
+}
+
+const defaultMaxColumns = 8
+
+// place places the families with NColumns columns, or with the number
+// of columns up to NColumns that uses the least page height.
+func (c *ColumnLayouter) place(families []FamilyBlock, bounds image.Rectangle, margin int) ([]PlacedFamily, error) {
+	if c.AutoColumns == false {
+		if c.NColumns < 1 {
+			return nil, fmt.Errorf("Invalid number of column")
+		}
+		return c.placeInColumns(families, bounds, margin, c.NColumns)
+	}
+	placed, nColumns, err := c.placeInBestColumns(families, bounds, margin)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Using %d columns, height used: %.2fmm", nColumns, c.drawer.ToMM(UsedHeight(placed, bounds)))
+	return placed, nil
+}
+
+// placeInBestColumns tries every number of columns up to NColumns, and
+// returns the placement using the least height and its number of
+// columns. The fewest columns win ties.
+func (c *ColumnLayouter) placeInBestColumns(families []FamilyBlock, bounds image.Rectangle, margin int) ([]PlacedFamily, int, error) {
+	maxColumns := c.NColumns
+	if maxColumns < 1 {
+		maxColumns = defaultMaxColumns
+	}
+	var best []PlacedFamily
+	bestHeight, bestColumns := 0, 0
+	var lastErr error
+	for n := 1; n <= maxColumns; n++ {
+		placed, err := c.placeInColumns(families, bounds, margin, n)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if h := UsedHeight(placed, bounds); best == nil || h < bestHeight {
+			best, bestHeight, bestColumns = placed, h, n
+		}
+	}
+	if best == nil {
+		return nil, 0, fmt.Errorf("Could not layout with up to %d columns: %s", maxColumns, lastErr)
+	}
+	return best, bestColumns, nil
+}
+
+// printableBounds returns the safe area of the page.
+func (c *ColumnLayouter) printableBounds() image.Rectangle {
+	return c.Page.SafeBounds(c.drawer)
+}
+
+func (c *ColumnLayouter) fillBackground() {
+	log.Printf("Filling background")
+	c.drawer.DrawRectangle(0, 0, c.drawer.ToDot(c.Page.Width), c.drawer.ToDot(c.Page.Height), color.White)
+	log.Printf("Done")
+}
+
+func (c *ColumnLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	if err := c.Page.Check(); err != nil {
+		return err
+	}
+	c.drawer = drawer
+
+	familyMarginDot := drawer.ToDot(c.FamilyMargin)
+	bounds := c.printableBounds()
+
+	placed, err := c.place(families, bounds, familyMarginDot)
+	if err != nil {
+		return err
+	}
+	log.Printf("Page utilization: %.1f%%", 100*PageUtilization(placed, bounds))
+
+	c.fillBackground()
+
+	for _, pf := range placed {
+		c.LayoutOne(pf)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package layout
+
+import (
+	"image"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type ColumnLayouterSuite struct {
+	Small, Big *families.TagFamily
+	Bounds     image.Rectangle
+}
+
+var _ = Suite(&ColumnLayouterSuite{})
+
+func (s *ColumnLayouterSuite) SetUpSuite(c *C) {
+	s.Small = &families.TagFamily{Name: "SMALL", TotalWidth: 10, Codes: make([]uint64, 1000)}
+	s.Big = &families.TagFamily{Name: "BIG", TotalWidth: 10, Codes: make([]uint64, 1000)}
+	// 100x100mm at 254 DPI, 10 dots per mm
+	s.Bounds = image.Rect(0, 0, 1000, 1000)
+}
+
+func (s *ColumnLayouterSuite) layouter() *ColumnLayouter {
+	res := &ColumnLayouter{
+		Page:         Page{Width: 100, Height: 100},
+		FamilyMargin: 2,
+		TagBorder:    0.2,
+	}
+	res.drawer = drawing.NewMeasureDrawer(254)
+	return res
+}
+
+func (s *ColumnLayouterSuite) block(tf *families.TagFamily, size float64, n int) FamilyBlock {
+	return FamilyBlock{Family: tf, Size: size, Ranges: []ranges.Range{{Begin: 0, End: n}}}
+}
+
+func (s *ColumnLayouterSuite) TestAdaptiveColumnsBalanceHeights(c *C) {
+	// BIG tags take 40+8 dots and SMALL ones 10+2 dots
+	blocks := []FamilyBlock{s.block(s.Small, 1, 200), s.block(s.Big, 4, 20)}
+	l := s.layouter()
+	l.AdaptiveColumnWidth = true
+	placed, err := l.placeInColumns(blocks, s.Bounds, 20, 2)
+	c.Assert(err, IsNil)
+	c.Assert(placed, HasLen, 2)
+
+	// BIG needs 26 slots with its label: 13 per row on 2 rows is the
+	// least height, SMALL takes the rest of the width
+	c.Check(placed[0].Family.Name, Equals, "BIG")
+	c.Check(placed[0].X, Equals, 0)
+	c.Check(placed[0].Width, Equals, 654)
+	c.Check(placed[0].NTagsPerRow, Equals, 13)
+	c.Check(placed[0].Height, Equals, 104)
+	c.Check(placed[1].Family.Name, Equals, "SMALL")
+	c.Check(placed[1].X, Equals, 674)
+	c.Check(placed[1].X+placed[1].Width, Equals, s.Bounds.Dx())
+	c.Check(placed[1].NTagsPerRow, Equals, 27)
+	c.Check(placed[1].Height, Equals, 98)
+
+	l.AdaptiveColumnWidth = false
+	equal, err := l.placeInColumns(blocks, s.Bounds, 20, 2)
+	c.Assert(err, IsNil)
+	c.Check(equal[0].Width, Equals, 490)
+	c.Check(UsedHeight(equal, s.Bounds) > UsedHeight(placed, s.Bounds), Equals, true)
+}
+
+func (s *ColumnLayouterSuite) TestAdaptiveColumnsRejectOptions(c *C) {
+	blocks := []FamilyBlock{s.block(s.Small, 1, 200)}
+	l := s.layouter()
+	l.AdaptiveColumnWidth = true
+	l.ReflowBlocks = true
+	_, err := l.placeInColumns(blocks, s.Bounds, 20, 2)
+	c.Check(err, ErrorMatches, "Adaptive column widths cannot reflow family blocks")
+
+	l.ReflowBlocks = false
+	l.Packing = "shelf"
+	_, err = l.placeInColumns(blocks, s.Bounds, 20, 2)
+	c.Check(err, ErrorMatches, "Adaptive column widths are only supported by the column packing")
+
+	l.Packing = ""
+	_, err = l.placeInColumns(blocks, image.Rect(0, 0, 21, 1000), 20, 2)
+	c.Check(err, ErrorMatches, "Invalid number of column")
+}
+
+func (s *ColumnLayouterSuite) TestAutoColumnsChoosesTheLeastHeight(c *C) {
+	blocks := []FamilyBlock{}
+	for i := 0; i < 4; i++ {
+		blocks = append(blocks, s.block(s.Small, 1, 100))
+	}
+	// a single column overflows 120 dots, 2 columns stack 2 blocks of
+	// 3 rows, 4 columns have a block of 6 rows each
+	bounds := image.Rect(0, 0, 1000, 120)
+	l := s.layouter()
+	l.AutoColumns = true
+	l.NColumns = 4
+	placed, nColumns, err := l.placeInBestColumns(blocks, bounds, 20)
+	c.Assert(err, IsNil)
+	c.Check(nColumns, Equals, 4)
+	c.Check(UsedHeight(placed, bounds), Equals, 74)
+
+	l.NColumns = 3
+	placed, nColumns, err = l.placeInBestColumns(blocks, bounds, 20)
+	c.Assert(err, IsNil)
+	c.Check(nColumns, Equals, 2)
+	c.Check(UsedHeight(placed, bounds), Equals, 96)
+
+	l.NColumns = 1
+	_, _, err = l.placeInBestColumns(blocks, bounds, 20)
+	c.Check(err, ErrorMatches, "Could not layout with up to 1 columns: Could not fill SMALL.*")
+
+	// adaptive columns balance the two families on 2 columns
+	l.NColumns = 3
+	l.AdaptiveColumnWidth = true
+	_, nColumns, err = l.placeInBestColumns([]FamilyBlock{s.block(s.Small, 1, 200), s.block(s.Big, 4, 20)}, s.Bounds, 20)
+	c.Assert(err, IsNil)
+	c.Check(nColumns, Equals, 2)
+}
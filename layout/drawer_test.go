@@ -0,0 +1,52 @@
+package layout
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+type recordedRectangle struct {
+	Layer  drawing.Layer
+	Bounds image.Rectangle
+}
+
+// recordingDrawer records the groups, labels and rectangles drawn by a
+// layouter.
+type recordingDrawer struct {
+	drawing.Drawer
+	Groups     []map[string]string
+	Labels     []string
+	Rectangles []recordedRectangle
+	layers     []drawing.Layer
+}
+
+func newRecordingDrawer(DPI int) *recordingDrawer {
+	return &recordingDrawer{Drawer: drawing.NewMeasureDrawer(DPI)}
+}
+
+func (d *recordingDrawer) BeginGroup(label string, metadata map[string]string) {
+	d.Groups = append(d.Groups, metadata)
+}
+
+func (d *recordingDrawer) Label(x, y, height int, label string, c color.Color) float64 {
+	d.Labels = append(d.Labels, label)
+	return 0.0
+}
+
+func (d *recordingDrawer) BeginLayer(l drawing.Layer) {
+	d.layers = append(d.layers, l)
+}
+
+func (d *recordingDrawer) EndLayer() {
+	d.layers = d.layers[:len(d.layers)-1]
+}
+
+func (d *recordingDrawer) DrawRectangle(x, y, w, h int, c color.Color) {
+	layer := drawing.TagLayer
+	if len(d.layers) > 0 {
+		layer = d.layers[len(d.layers)-1]
+	}
+	d.Rectangles = append(d.Rectangles, recordedRectangle{Layer: layer, Bounds: image.Rect(x, y, x+w, y+h)})
+}
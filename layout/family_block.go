@@ -0,0 +1,129 @@
+package layout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+type FamilyBlock struct {
+	Family *families.TagFamily
+	Size   float64
+	Ranges []ranges.Range
+	// Filtered is true if the IDs were selected by filters
+	Filtered bool
+}
+
+// Copies returns the number of copies of each ID if it is the same
+// for all ranges, or 0.
+func (f *FamilyBlock) Copies() int {
+	res := 0
+	for _, r := range f.Ranges {
+		if res != 0 && res != r.NumberOfCopies() {
+			return 0
+		}
+		res = r.NumberOfCopies()
+	}
+	return res
+}
+
+func (f *FamilyBlock) FamilyLabelActualSize(size float64) string {
+	if copies := f.Copies(); copies > 1 {
+		return fmt.Sprintf("%s %.2fMM X%d", f.Family.Name, size, copies)
+	}
+	return fmt.Sprintf("%s %.2fMM", f.Family.Name, size)
+}
+
+func (f *FamilyBlock) FamilyLabel() string {
+	return f.FamilyLabelActualSize(f.Size)
+}
+
+func (f *FamilyBlock) NumberOfTags() int {
+	n := 0
+	for _, r := range f.Ranges {
+		n += r.NumberOfTags()
+	}
+	return n
+}
+
+// Metadata describes the block drawn with tags of size mm.
+func (f *FamilyBlock) Metadata(size float64) map[string]string {
+	return map[string]string{
+		"family":      f.Family.Name,
+		"size":        fmt.Sprintf("%.2f", f.Size),
+		"actual-size": fmt.Sprintf("%.2f", size),
+		"ranges":      f.RangeString(),
+		"tags":        fmt.Sprintf("%d", f.NumberOfTags()),
+	}
+}
+
+func (f *FamilyBlock) RangeString() string {
+	res := ""
+	sep := ""
+	for _, r := range f.Ranges {
+		res = fmt.Sprintf("%s%s%s", res, sep, r)
+		sep = ";"
+	}
+	return res
+}
+
+// ExtractFamilyAndSizes parses the block of each specification of list,
+// in order.
+func ExtractFamilyAndSizes(list []string) ([]FamilyBlock, error) {
+	res := []FamilyBlock{}
+	for _, fAndSize := range list {
+		// the range may contain ':' for steps
+		fargs := strings.SplitN(fAndSize, ":", 3)
+		if len(fargs) <= 1 {
+			return res, fmt.Errorf("invalid family specification '%s': need at list family and size in the form '<name>:<size>'", fAndSize)
+		}
+		tf, err := families.GetFamily(fargs[0])
+		if err != nil {
+			return res, err
+		}
+		s, err := strconv.ParseFloat(fargs[1], 64)
+		if err != nil {
+			return res, err
+		}
+
+		if len(fargs) == 2 {
+			res = append(res, FamilyBlock{
+				Family: tf,
+				Size:   s,
+				Ranges: []ranges.Range{
+					ranges.Range{
+						Begin: 0,
+						End:   len(tf.Codes),
+					},
+				},
+			})
+			continue
+		}
+
+		expr, err := ranges.ParseRangeExpression(fargs[2])
+		if err != nil {
+			return res, err
+		}
+		if expr.IsEmpty() {
+			return res, fmt.Errorf("Range for '%s' cannot be empty", fAndSize)
+		}
+		ids, err := expr.Select(tf)
+		if err != nil {
+			return res, err
+		}
+		if len(ids) == 0 {
+			return res, fmt.Errorf("No tag of %s is selected by '%s'", fargs[0], fargs[2])
+		}
+
+		res = append(res, FamilyBlock{
+			Family:   tf,
+			Size:     s,
+			Ranges:   ranges.RangesOfIDs(ids),
+			Filtered: expr.HasFilters(),
+		})
+	}
+	return res, nil
+}
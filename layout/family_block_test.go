@@ -0,0 +1,27 @@
+package layout
+
+import (
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type FamilyBlockSuite struct{}
+
+var _ = Suite(&FamilyBlockSuite{})
+
+func (s *FamilyBlockSuite) TestExtractsBlocksInOrder(c *C) {
+	blocks, err := ExtractFamilyAndSizes([]string{"36h11:2.0:0-9", "36h10:1.5", "36h11:2.0:0-99;balance<=6"})
+	c.Assert(err, IsNil)
+	c.Assert(blocks, HasLen, 3)
+	c.Check(blocks[0].Family.Name, Equals, "36H11")
+	c.Check(blocks[0].Ranges, DeepEquals, []ranges.Range{{Begin: 0, End: 10}})
+	c.Check(blocks[0].Filtered, Equals, false)
+	c.Check(blocks[1].Family.Name, Equals, "36H10")
+	c.Check(blocks[1].Size, Equals, 1.5)
+	c.Check(blocks[1].Filtered, Equals, false)
+	c.Check(blocks[2].Filtered, Equals, true)
+	c.Check(blocks[2].NumberOfTags() <= 100, Equals, true)
+
+	_, err = ExtractFamilyAndSizes([]string{"36h11"})
+	c.Check(err, ErrorMatches, "invalid family specification '36h11'.*")
+}
@@ -0,0 +1,62 @@
+package layout
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+// FillLayouter fills the printable area of the page with as many
+// consecutive IDs of a single family as possible, starting at From.
+// LastID is the last ID placed after Layout.
+type FillLayouter struct {
+	ColumnLayouter
+	From   int
+	LastID int
+}
+
+func (l *FillLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	if len(families) != 1 {
+		return fmt.Errorf("Fill layouter only supports a single family (got:%d)", len(families))
+	}
+	if err := l.Page.Check(); err != nil {
+		return err
+	}
+	l.drawer = drawer
+	f := families[0]
+	nCodes := len(f.Family.Codes)
+	if l.From < 0 || l.From >= nCodes {
+		return fmt.Errorf("%d is out of range for %s (%d codes)", l.From, f.Family.Name, nCodes)
+	}
+
+	bounds := l.printableBounds()
+	f.Ranges = []ranges.Range{ranges.Range{Begin: l.From, End: nCodes}}
+	pf := l.ComputeFamilySize(f, bounds.Dx())
+	if pf.NTagsPerRow < 1 {
+		return fmt.Errorf("Page is too narrow for %s:%.2f", f.Family.Name, f.Size)
+	}
+	nbRows := (bounds.Dy() - pf.ActualBorderWidth) / (pf.ActualTagWidth + pf.ActualBorderWidth)
+	n := min(nbRows*pf.NTagsPerRow-pf.Skips, nCodes-l.From)
+	if n < 1 {
+		return fmt.Errorf("Page is too small for a single %s:%.2f tag", f.Family.Name, f.Size)
+	}
+
+	f.Ranges = []ranges.Range{ranges.Range{Begin: l.From, End: l.From + n}}
+	pf = l.ComputeFamilySize(f, bounds.Dx())
+	pf.X = bounds.Min.X
+	pf.Y = bounds.Min.Y
+
+	l.fillBackground()
+	l.LayoutOne(pf)
+
+	l.LastID = l.From + n - 1
+	log.Printf("Placed %d tags of %s, last ID is %d", n, f.Family.Name, l.LastID)
+	if l.LastID+1 < nCodes {
+		log.Printf("Continue on the next sheet with --fill-from %d", l.LastID+1)
+	} else {
+		log.Printf("All the tags of %s are placed", f.Family.Name)
+	}
+	return nil
+}
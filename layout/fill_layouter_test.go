@@ -0,0 +1,65 @@
+package layout
+
+import (
+	"fmt"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	. "gopkg.in/check.v1"
+)
+
+type FillLayouterSuite struct {
+	Family *families.TagFamily
+}
+
+var _ = Suite(&FillLayouterSuite{})
+
+func (s *FillLayouterSuite) SetUpSuite(c *C) {
+	s.Family = &families.TagFamily{Name: "TEST", TotalWidth: 10, Codes: make([]uint64, 2000)}
+}
+
+func (s *FillLayouterSuite) layout(from int, page Page) (*FillLayouter, *recordingDrawer, error) {
+	l := &FillLayouter{ColumnLayouter: ColumnLayouter{Page: page, TagBorder: 0.2}, From: from}
+	d := newRecordingDrawer(254)
+	return l, d, l.Layout(d, []FamilyBlock{{Family: s.Family, Size: 1}})
+}
+
+func (s *FillLayouterSuite) TestFillsThePage(c *C) {
+	// at 10 dots per mm, tags take 10+2 dots: 41 tags per row on 24
+	// rows, minus 6 slots for the label 'TEST 1.00MM'
+	page := Page{Width: 50, Height: 30}
+	testdata := []struct {
+		From   int
+		Ranges string
+		LastID int
+	}{
+		{0, "0-977", 977},
+		{10, "10-987", 987},
+		{988, "988-1965", 1965},
+		{1966, "1966-1999", 1999},
+	}
+	for _, d := range testdata {
+		l, drawer, err := s.layout(d.From, page)
+		c.Assert(err, IsNil)
+		c.Assert(drawer.Groups, HasLen, 1)
+		c.Check(drawer.Groups[0]["ranges"], Equals, d.Ranges)
+		c.Check(drawer.Groups[0]["tags"], Equals, fmt.Sprintf("%d", d.LastID-d.From+1))
+		c.Check(l.LastID, Equals, d.LastID)
+	}
+}
+
+func (s *FillLayouterSuite) TestFillErrors(c *C) {
+	page := Page{Width: 50, Height: 30}
+	for _, from := range []int{-1, 2000} {
+		_, _, err := s.layout(from, page)
+		c.Check(err, ErrorMatches, fmt.Sprintf("%d is out of range for TEST \\(2000 codes\\)", from))
+	}
+	_, _, err := s.layout(0, Page{Width: 50, Height: 1})
+	c.Check(err, ErrorMatches, "Page is too small for a single TEST:1.00 tag")
+	_, _, err = s.layout(0, Page{Width: 1, Height: 30})
+	c.Check(err, ErrorMatches, "Page is too narrow for TEST:1.00")
+
+	l := &FillLayouter{ColumnLayouter: ColumnLayouter{Page: page, TagBorder: 0.2}}
+	block := FamilyBlock{Family: s.Family, Size: 1}
+	err = l.Layout(newRecordingDrawer(254), []FamilyBlock{block, block})
+	c.Check(err, ErrorMatches, "Fill layouter only supports a single family \\(got:2\\)")
+}
@@ -0,0 +1,76 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+// ImpositionLayouter repeats the layout of Block, of BlockWidth x
+// BlockHeight mm, on a grid centered in the safe area of the Page with
+// Gutter mm between two blocks. Count limits the number of blocks, 0
+// means as many as fit.
+type ImpositionLayouter struct {
+	Block       Layouter
+	BlockWidth  float64
+	BlockHeight float64
+	Page        Page
+	Gutter      float64
+	Count       int
+}
+
+type impositionGrid struct {
+	NX, NY int
+	X0, Y0 float64
+}
+
+func (l *ImpositionLayouter) grid() (impositionGrid, error) {
+	res := impositionGrid{}
+	if l.BlockWidth <= 0 || l.BlockHeight <= 0 || l.Gutter < 0 {
+		return res, fmt.Errorf("Invalid block size %.2fx%.2fmm or gutter %.2fmm", l.BlockWidth, l.BlockHeight, l.Gutter)
+	}
+	if err := l.Page.Check(); err != nil {
+		return res, err
+	}
+	x, y, width, height := l.Page.SafeArea()
+	res.NX = int((width + l.Gutter) / (l.BlockWidth + l.Gutter))
+	res.NY = int((height + l.Gutter) / (l.BlockHeight + l.Gutter))
+	if res.NX < 1 || res.NY < 1 {
+		return res, fmt.Errorf("Block of %.2fx%.2fmm does not fit in %.2fx%.2fmm", l.BlockWidth, l.BlockHeight, width, height)
+	}
+	if l.Count > 0 {
+		res.NX = min(res.NX, l.Count)
+		res.NY = min(res.NY, (l.Count+res.NX-1)/res.NX)
+	}
+	res.X0 = x + (width-float64(res.NX)*(l.BlockWidth+l.Gutter)+l.Gutter)/2
+	res.Y0 = y + (height-float64(res.NY)*(l.BlockHeight+l.Gutter)+l.Gutter)/2
+	return res, nil
+}
+
+func (l *ImpositionLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	g, err := l.grid()
+	if err != nil {
+		return err
+	}
+	n := g.NX * g.NY
+	if l.Count > 0 {
+		n = min(n, l.Count)
+	}
+	log.Printf("Imposing %d blocks of %.2fx%.2fmm (%dx%d) on %.2fx%.2fmm", n, l.BlockWidth, l.BlockHeight, g.NX, g.NY, l.Page.Width, l.Page.Height)
+
+	drawer.DrawRectangle(0, 0, drawer.ToDot(l.Page.Width), drawer.ToDot(l.Page.Height), color.White)
+	for i := 0; i < n; i++ {
+		x := g.X0 + float64(i%g.NX)*(l.BlockWidth+l.Gutter)
+		y := g.Y0 + float64(i/g.NX)*(l.BlockHeight+l.Gutter)
+		log.Printf("Block %d/%d", i+1, n)
+		drawer.RotateTranslate(drawer.ToDot(x), drawer.ToDot(y), 0.0)
+		err := l.Block.Layout(drawer, families)
+		drawer.EndRotateTranslate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
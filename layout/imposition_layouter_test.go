@@ -0,0 +1,43 @@
+package layout
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ImpositionLayouterSuite struct{}
+
+var _ = Suite(&ImpositionLayouterSuite{})
+
+func (s *ImpositionLayouterSuite) TestImpositionGrid(c *C) {
+	l := &ImpositionLayouter{
+		BlockWidth:  60,
+		BlockHeight: 40,
+		Page:        Page{Width: 297, Height: 420},
+		Gutter:      5,
+	}
+	g, err := l.grid()
+	c.Assert(err, IsNil)
+	c.Check(g.NX, Equals, 4)
+	c.Check(g.NY, Equals, 9)
+	c.Check(g.X0, Equals, (297.0-4*60-3*5)/2)
+	c.Check(g.Y0, Equals, (420.0-9*40-8*5)/2)
+
+	l.Count = 6
+	g, err = l.grid()
+	c.Assert(err, IsNil)
+	c.Check(g.NX, Equals, 4)
+	c.Check(g.NY, Equals, 2)
+
+	l.Count = 0
+	l.Page.Margins = Margins{Top: 10, Right: 5, Bottom: 30, Left: 12}
+	g, err = l.grid()
+	c.Assert(err, IsNil)
+	c.Check(g.NX, Equals, 4)
+	c.Check(g.NY, Equals, 8)
+	c.Check(g.X0, Equals, 12+(280.0-4*60-3*5)/2)
+	c.Check(g.Y0, Equals, 10+(380.0-8*40-7*5)/2)
+
+	l.BlockWidth = 300
+	_, err = l.grid()
+	c.Check(err, ErrorMatches, "Block of 300.00x40.00mm does not fit in 280.00x380.00mm")
+}
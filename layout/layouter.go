@@ -0,0 +1,17 @@
+// Package layout places blocks of tags of a family on a page.
+package layout
+
+import (
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+type Layouter interface {
+	Layout(drawer drawing.Drawer, families []FamilyBlock) error
+}
+
+// ContentSizedLayouter is a Layouter whose page height depends on the
+// families it lays out, e.g. on continuous roll media.
+type ContentSizedLayouter interface {
+	Layouter
+	ContentHeight(DPI int, families []FamilyBlock) (float64, error)
+}
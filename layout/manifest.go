@@ -0,0 +1,87 @@
+package layout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+type ManifestFamily struct {
+	Family       string  `json:"family"`
+	Size         float64 `json:"size"`
+	ActualSize   float64 `json:"actual_size"`
+	Ranges       string  `json:"ranges"`
+	Copies       int     `json:"copies,omitempty"`
+	NumberOfTags int     `json:"tags"`
+}
+
+// Manifest describes how a sheet was produced. Sizes are in mm. Its
+// ID is derived from its content and printed on the sheet.
+type Manifest struct {
+	ID       string           `json:"id"`
+	Job      string           `json:"job"`
+	Date     time.Time        `json:"date"`
+	Version  string           `json:"version"`
+	DPI      int              `json:"dpi"`
+	Output   string           `json:"output"`
+	Page     Page             `json:"page"`
+	Families []ManifestFamily `json:"families"`
+}
+
+// ActualTagSize returns the size in mm of the tags of f once rounded
+// to a whole number of dots per bit.
+func ActualTagSize(DPI int, f FamilyBlock) float64 {
+	c := &ColumnLayouter{drawer: drawing.NewMeasureDrawer(DPI)}
+	tagDot, _, _ := c.PerfectPixelSizeMM(f.Size, 0.0, 0.0, f.Family.TotalWidth)
+	return c.drawer.ToMM(tagDot)
+}
+
+func NewManifest(job string, date time.Time, DPI int, output string, page Page, families []FamilyBlock) *Manifest {
+	res := &Manifest{
+		Job:      job,
+		Date:     date.UTC().Truncate(time.Second),
+		Version:  Version,
+		DPI:      DPI,
+		Output:   output,
+		Page:     page,
+		Families: make([]ManifestFamily, 0, len(families)),
+	}
+	for _, f := range families {
+		res.Families = append(res.Families, ManifestFamily{
+			Family:       f.Family.Name,
+			Size:         f.Size,
+			ActualSize:   ActualTagSize(DPI, f),
+			Ranges:       f.RangeString(),
+			Copies:       f.Copies(),
+			NumberOfTags: f.NumberOfTags(),
+		})
+	}
+	res.ID = res.computeID()
+	return res
+}
+
+// computeID returns the first 12 hexadecimal digits of the SHA-256 of
+// the manifest without its ID.
+func (m *Manifest) computeID() string {
+	withoutID := *m
+	withoutID.ID = ""
+	data, err := json.Marshal(withoutID)
+	if err != nil {
+		// a Manifest only holds marshallable types
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,45 @@
+package layout
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type ManifestSuite struct{}
+
+var _ = Suite(&ManifestSuite{})
+
+func (s *ManifestSuite) TestManifest(c *C) {
+	tf := &families.TagFamily{Name: "TEST", TotalWidth: 8, Codes: make([]uint64, 100)}
+	families := []FamilyBlock{{Family: tf, Size: 2.0, Ranges: []ranges.Range{{Begin: 0, End: 10, Copies: 2}}}}
+	date := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	page := Page{Width: 210, Height: 297, Margins: UniformMargins(20)}
+
+	m := NewManifest("job", date, 1200, "out.png", page, families)
+	c.Check(m.ID, Matches, "[0-9a-f]{12}")
+	c.Check(NewManifest("job", date, 1200, "out.png", page, families).ID, Equals, m.ID)
+	c.Check(NewManifest("other", date, 1200, "out.png", page, families).ID, Not(Equals), m.ID)
+	c.Check(m.Families, DeepEquals, []ManifestFamily{{
+		Family:       "TEST",
+		Size:         2.0,
+		ActualSize:   ActualTagSize(1200, families[0]),
+		Ranges:       "0-9x2",
+		Copies:       2,
+		NumberOfTags: 20,
+	}})
+
+	path := filepath.Join(c.MkDir(), "manifest.json")
+	c.Assert(m.Write(path), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	read := &Manifest{}
+	c.Assert(json.Unmarshal(data, read), IsNil)
+	c.Check(read, DeepEquals, m)
+	c.Check(read.computeID(), Equals, m.ID)
+}
@@ -0,0 +1,354 @@
+package layout
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Packer places families in bounds, keeping margin between them. Each
+// entry of families lists the acceptable shapes of one family, the
+// first one being the preferred. Packers set X and Y of the chosen
+// shape.
+type Packer interface {
+	Pack(families [][]PlacedFamily, bounds image.Rectangle, margin int) ([]PlacedFamily, error)
+}
+
+// PackingStrategies lists the names accepted by NewPacker.
+var PackingStrategies = []string{"column", "shelf", "guillotine", "maxrects"}
+
+func NewPacker(name string, nColumns int) (Packer, error) {
+	switch name {
+	case "", "column":
+		return &ColumnPacker{NColumns: nColumns}, nil
+	case "shelf":
+		return &ShelfPacker{}, nil
+	case "guillotine":
+		return &GuillotinePacker{}, nil
+	case "maxrects":
+		return &MaxRectsPacker{}, nil
+	}
+	return nil, fmt.Errorf("Unknown packing strategy '%s'", name)
+}
+
+func errCouldNotFill(pf PlacedFamily) error {
+	return fmt.Errorf("Could not fill %s:%.2f:%s in layout", pf.Family.Name, pf.Size, pf.RangeString())
+}
+
+// ColumnPacker fills NColumns columns of equal width. Families taking
+// a full column width are stacked first, by decreasing height, then
+// the others are put in rows by increasing width.
+type ColumnPacker struct {
+	NColumns int
+}
+
+type packingColumn struct {
+	X, Width  int
+	RowX      int
+	RowBottom int
+	Y         int
+}
+
+func (p *ColumnPacker) Pack(families [][]PlacedFamily, bounds image.Rectangle, margin int) ([]PlacedFamily, error) {
+	if p.NColumns < 1 {
+		return nil, fmt.Errorf("Invalid number of column")
+	}
+	columnWidth := (bounds.Dx() - margin*(p.NColumns-1)) / p.NColumns
+
+	fullWidth := []PlacedFamily{}
+	incompleteWidth := []PlacedFamily{}
+	for _, shapes := range families {
+		pf := shapes[0]
+		if pf.Width < columnWidth {
+			incompleteWidth = append(incompleteWidth, pf)
+		} else {
+			fullWidth = append(fullWidth, pf)
+		}
+	}
+	sort.Stable(sort.Reverse(PlacedFamilyListByHeight(fullWidth)))
+	sort.Stable(PlacedFamilyListByWidth(incompleteWidth))
+
+	columns := make([]packingColumn, p.NColumns)
+	for i := range columns {
+		columns[i] = packingColumn{
+			X:         i * (columnWidth + margin),
+			Width:     columnWidth,
+			Y:         -margin,
+			RowBottom: -margin,
+		}
+	}
+
+	res := make([]PlacedFamily, 0, len(families))
+	for _, pf := range fullWidth {
+		fitted := false
+		for i := range columns {
+			col := &columns[i]
+			y := col.RowBottom + margin
+			if y+pf.Height > bounds.Dy() {
+				continue
+			}
+			pf.X = bounds.Min.X + col.X
+			pf.Y = bounds.Min.Y + y
+			col.Y = y + pf.Height
+			col.RowBottom = col.Y
+			res = append(res, pf)
+			fitted = true
+			break
+		}
+		if fitted == false {
+			return nil, errCouldNotFill(pf)
+		}
+	}
+
+	for _, pf := range incompleteWidth {
+		fitted := false
+		for i := range columns {
+			col := &columns[i]
+			y := col.Y + margin
+			if y+pf.Height > bounds.Dy() {
+				//not fitting in height anyway
+				continue
+			}
+			if col.RowX+pf.Width > col.Width {
+				//we terminate the row, and check if it still fits in height
+				col.Y = col.RowBottom
+				col.RowX = 0
+				y = col.Y + margin
+				if y+pf.Height > bounds.Dy() {
+					continue
+				}
+			}
+			pf.X = bounds.Min.X + col.X + col.RowX
+			pf.Y = bounds.Min.Y + y
+			col.RowX += pf.Width + margin
+			col.RowBottom = max(col.RowBottom, y+pf.Height)
+			res = append(res, pf)
+			fitted = true
+			break
+		}
+		if fitted == false {
+			return nil, errCouldNotFill(pf)
+		}
+	}
+
+	return res, nil
+}
+
+// sortByArea returns the indexes of families sorted by decreasing
+// area of their preferred shape.
+func sortByArea(families [][]PlacedFamily) []int {
+	res := make([]int, len(families))
+	for i := range res {
+		res[i] = i
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := families[res[i]][0], families[res[j]][0]
+		return a.Width*a.Height > b.Width*b.Height
+	})
+	return res
+}
+
+type shelf struct {
+	Y, Height, X int
+}
+
+// ShelfPacker puts families on shelves spanning the whole bounds
+// width, by decreasing height.
+type ShelfPacker struct{}
+
+func (p *ShelfPacker) Pack(families [][]PlacedFamily, bounds image.Rectangle, margin int) ([]PlacedFamily, error) {
+	order := make([]int, len(families))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return families[order[i]][0].Height > families[order[j]][0].Height
+	})
+
+	W := bounds.Dx() + margin
+	H := bounds.Dy() + margin
+	shelves := []shelf{}
+	nextY := 0
+	res := make([]PlacedFamily, 0, len(families))
+	for _, idx := range order {
+		bestShelf, bestShape, bestWaste := -1, -1, 0
+		for s, sh := range shelves {
+			for i, pf := range families[idx] {
+				w, h := pf.Width+margin, pf.Height+margin
+				if sh.X+w > W || h > sh.Height {
+					continue
+				}
+				if waste := sh.Height - h; bestShelf < 0 || waste < bestWaste {
+					bestShelf, bestShape, bestWaste = s, i, waste
+				}
+			}
+		}
+		if bestShelf < 0 {
+			for i, pf := range families[idx] {
+				w, h := pf.Width+margin, pf.Height+margin
+				if w > W || nextY+h > H {
+					continue
+				}
+				if bestShape < 0 || h < families[idx][bestShape].Height+margin {
+					bestShape = i
+				}
+			}
+			if bestShape < 0 {
+				return nil, errCouldNotFill(families[idx][0])
+			}
+			shelves = append(shelves, shelf{Y: nextY, Height: families[idx][bestShape].Height + margin})
+			nextY += families[idx][bestShape].Height + margin
+			bestShelf = len(shelves) - 1
+		}
+		pf := families[idx][bestShape]
+		pf.X = bounds.Min.X + shelves[bestShelf].X
+		pf.Y = bounds.Min.Y + shelves[bestShelf].Y
+		shelves[bestShelf].X += pf.Width + margin
+		res = append(res, pf)
+	}
+	return res, nil
+}
+
+// GuillotinePacker places each family in the free rectangle that
+// fits it best, and splits the leftover along its shorter axis.
+type GuillotinePacker struct{}
+
+func (p *GuillotinePacker) Pack(families [][]PlacedFamily, bounds image.Rectangle, margin int) ([]PlacedFamily, error) {
+	free := []image.Rectangle{image.Rect(0, 0, bounds.Dx()+margin, bounds.Dy()+margin)}
+	res := make([]PlacedFamily, 0, len(families))
+	for _, idx := range sortByArea(families) {
+		bestFree, bestShape, bestScore := -1, -1, 0
+		for f, r := range free {
+			for i, pf := range families[idx] {
+				w, h := pf.Width+margin, pf.Height+margin
+				if w > r.Dx() || h > r.Dy() {
+					continue
+				}
+				if score := r.Dx()*r.Dy() - w*h; bestFree < 0 || score < bestScore {
+					bestFree, bestShape, bestScore = f, i, score
+				}
+			}
+		}
+		if bestFree < 0 {
+			return nil, errCouldNotFill(families[idx][0])
+		}
+		pf := families[idx][bestShape]
+		r := free[bestFree]
+		w, h := pf.Width+margin, pf.Height+margin
+		pf.X = bounds.Min.X + r.Min.X
+		pf.Y = bounds.Min.Y + r.Min.Y
+		res = append(res, pf)
+
+		var right, bottom image.Rectangle
+		if r.Dx()-w < r.Dy()-h {
+			right = image.Rect(r.Min.X+w, r.Min.Y, r.Max.X, r.Min.Y+h)
+			bottom = image.Rect(r.Min.X, r.Min.Y+h, r.Max.X, r.Max.Y)
+		} else {
+			right = image.Rect(r.Min.X+w, r.Min.Y, r.Max.X, r.Max.Y)
+			bottom = image.Rect(r.Min.X, r.Min.Y+h, r.Min.X+w, r.Max.Y)
+		}
+		free = append(free[:bestFree], free[bestFree+1:]...)
+		for _, n := range []image.Rectangle{right, bottom} {
+			if n.Empty() == false {
+				free = append(free, n)
+			}
+		}
+	}
+	return res, nil
+}
+
+// MaxRectsPacker keeps all the maximal free rectangles and places each
+// family where the shorter leftover side is the smallest.
+type MaxRectsPacker struct{}
+
+func (p *MaxRectsPacker) Pack(families [][]PlacedFamily, bounds image.Rectangle, margin int) ([]PlacedFamily, error) {
+	free := []image.Rectangle{image.Rect(0, 0, bounds.Dx()+margin, bounds.Dy()+margin)}
+	res := make([]PlacedFamily, 0, len(families))
+	for _, idx := range sortByArea(families) {
+		bestFree, bestShape, bestShort, bestLong := -1, -1, 0, 0
+		for f, r := range free {
+			for i, pf := range families[idx] {
+				w, h := pf.Width+margin, pf.Height+margin
+				if w > r.Dx() || h > r.Dy() {
+					continue
+				}
+				short, long := r.Dx()-w, r.Dy()-h
+				if short > long {
+					short, long = long, short
+				}
+				if bestFree < 0 || short < bestShort || (short == bestShort && long < bestLong) {
+					bestFree, bestShape, bestShort, bestLong = f, i, short, long
+				}
+			}
+		}
+		if bestFree < 0 {
+			return nil, errCouldNotFill(families[idx][0])
+		}
+		pf := families[idx][bestShape]
+		used := image.Rect(0, 0, pf.Width+margin, pf.Height+margin).Add(free[bestFree].Min)
+		pf.X = bounds.Min.X + used.Min.X
+		pf.Y = bounds.Min.Y + used.Min.Y
+		res = append(res, pf)
+
+		newFree := make([]image.Rectangle, 0, len(free)+4)
+		for _, r := range free {
+			if r.Overlaps(used) == false {
+				newFree = append(newFree, r)
+				continue
+			}
+			// image.Rect would reorder the coordinates of empty splits
+			splits := []image.Rectangle{
+				{Min: r.Min, Max: image.Pt(used.Min.X, r.Max.Y)},
+				{Min: image.Pt(used.Max.X, r.Min.Y), Max: r.Max},
+				{Min: r.Min, Max: image.Pt(r.Max.X, used.Min.Y)},
+				{Min: image.Pt(r.Min.X, used.Max.Y), Max: r.Max},
+			}
+			for _, s := range splits {
+				if s.Empty() == false {
+					newFree = append(newFree, s)
+				}
+			}
+		}
+		free = pruneContained(newFree)
+	}
+	return res, nil
+}
+
+func pruneContained(rects []image.Rectangle) []image.Rectangle {
+	res := make([]image.Rectangle, 0, len(rects))
+	for i, r := range rects {
+		contained := false
+		for j, o := range rects {
+			if i == j || r.In(o) == false {
+				continue
+			}
+			// keeps only the first of two identical rectangles
+			if r.Eq(o) == false || j < i {
+				contained = true
+				break
+			}
+		}
+		if contained == false {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// PageUtilization returns the ratio of bounds covered by families.
+func PageUtilization(families []PlacedFamily, bounds image.Rectangle) float64 {
+	used := 0
+	for _, pf := range families {
+		used += pf.Width * pf.Height
+	}
+	return float64(used) / float64(bounds.Dx()*bounds.Dy())
+}
+
+// UsedHeight returns the height of bounds used by families.
+func UsedHeight(families []PlacedFamily, bounds image.Rectangle) int {
+	res := 0
+	for _, pf := range families {
+		res = max(res, pf.Y+pf.Height-bounds.Min.Y)
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package layout
+
+import (
+	"image"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	. "gopkg.in/check.v1"
+)
+
+type PackingSuite struct {
+	Families [][]PlacedFamily
+}
+
+var _ = Suite(&PackingSuite{})
+
+func (s *PackingSuite) SetUpTest(c *C) {
+	sizes := [][2]int{{99, 30}, {99, 50}, {40, 20}, {30, 20}, {55, 10}, {20, 45}, {45, 20}}
+	s.Families = nil
+	for _, size := range sizes {
+		pf := PlacedFamily{
+			FamilyBlock: FamilyBlock{Family: &families.TagFamily{Name: "TEST"}},
+			Width:       size[0],
+			Height:      size[1],
+		}
+		s.Families = append(s.Families, []PlacedFamily{pf})
+	}
+}
+
+func (s *PackingSuite) TestPackersDoNotOverlap(c *C) {
+	bounds := image.Rect(10, 10, 210, 130)
+	margin := 2
+	for _, name := range PackingStrategies {
+		packer, err := NewPacker(name, 2)
+		c.Assert(err, IsNil)
+		placed, err := packer.Pack(s.Families, bounds, margin)
+		if c.Check(err, IsNil, Commentf("packing with %s", name)) == false {
+			continue
+		}
+		c.Check(placed, HasLen, len(s.Families))
+		for i, a := range placed {
+			ra := image.Rect(a.X, a.Y, a.X+a.Width, a.Y+a.Height)
+			c.Check(ra.In(bounds), Equals, true, Commentf("%s: %v is not in %v", name, ra, bounds))
+			for _, b := range placed[i+1:] {
+				rb := image.Rect(b.X, b.Y, b.X+b.Width, b.Y+b.Height)
+				c.Check(ra.Inset(-margin).Overlaps(rb), Equals, false, Commentf("%s: %v is too close to %v", name, ra, rb))
+			}
+		}
+	}
+}
+
+func (s *PackingSuite) TestPackersUseAlternativeShapes(c *C) {
+	bounds := image.Rect(0, 0, 100, 40)
+	tall := PlacedFamily{FamilyBlock: FamilyBlock{Family: &families.TagFamily{Name: "TEST"}}, Width: 20, Height: 60}
+	wide := tall
+	wide.Width, wide.Height = 60, 20
+	for _, name := range []string{"shelf", "guillotine", "maxrects"} {
+		packer, err := NewPacker(name, 1)
+		c.Assert(err, IsNil)
+		_, err = packer.Pack([][]PlacedFamily{{tall}}, bounds, 0)
+		c.Check(err, ErrorMatches, "Could not fill TEST.*")
+		placed, err := packer.Pack([][]PlacedFamily{{tall, wide}}, bounds, 0)
+		c.Assert(err, IsNil, Commentf("packing with %s", name))
+		c.Check(placed[0].Width, Equals, 60)
+	}
+}
+
+func (s *PackingSuite) TestPackerFailsWhenFull(c *C) {
+	for _, name := range PackingStrategies {
+		packer, err := NewPacker(name, 1)
+		c.Assert(err, IsNil)
+		_, err = packer.Pack(s.Families, image.Rect(0, 0, 100, 100), 2)
+		c.Check(err, ErrorMatches, "Could not fill TEST.*", Commentf("packing with %s", name))
+	}
+}
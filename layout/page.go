@@ -0,0 +1,144 @@
+package layout
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+// Margins are the widths in mm of the non-printable zones on each side
+// of a page.
+type Margins struct {
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+}
+
+func UniformMargins(v float64) Margins {
+	return Margins{Top: v, Right: v, Bottom: v, Left: v}
+}
+
+// ExtractMargins parses one to four comma separated values in mm,
+// which set the margins in the order top, right, bottom, left. Like
+// CSS, missing values are taken from the opposite side.
+func ExtractMargins(s string) (Margins, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) > 4 {
+		return Margins{}, fmt.Errorf("Invalid margins '%s': expected 1 to 4 values", s)
+	}
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return Margins{}, fmt.Errorf("Invalid margins '%s': %s", s, err)
+		}
+		if v < 0 {
+			return Margins{}, fmt.Errorf("Invalid margins '%s': margins cannot be negative", s)
+		}
+		values[i] = v
+	}
+	switch len(values) {
+	case 1:
+		return UniformMargins(values[0]), nil
+	case 2:
+		return Margins{Top: values[0], Right: values[1], Bottom: values[0], Left: values[1]}, nil
+	case 3:
+		return Margins{Top: values[0], Right: values[1], Bottom: values[2], Left: values[1]}, nil
+	}
+	return Margins{Top: values[0], Right: values[1], Bottom: values[2], Left: values[3]}, nil
+}
+
+// Page is the physical sheet of Width x Height mm, once trimmed.
+// Layouters draw on the trimmed page and only place tags in its safe
+// area: within the Margins the printer cannot reach, inset by Safe. The
+// canvas extends the trimmed page by Bleed on each side.
+type Page struct {
+	Width   float64 `json:"width"`
+	Height  float64 `json:"height"`
+	Bleed   float64 `json:"bleed"`
+	Margins Margins `json:"margins"`
+	Safe    float64 `json:"safe"`
+}
+
+func (p Page) CanvasWidth() float64 {
+	return p.Width + 2*p.Bleed
+}
+
+func (p Page) CanvasHeight() float64 {
+	return p.Height + 2*p.Bleed
+}
+
+// SafeArea returns the position and size in mm of the safe area on
+// the trimmed page.
+func (p Page) SafeArea() (x, y, width, height float64) {
+	x = p.Margins.Left + p.Safe
+	y = p.Margins.Top + p.Safe
+	width = p.Width - x - p.Margins.Right - p.Safe
+	height = p.Height - y - p.Margins.Bottom - p.Safe
+	return x, y, width, height
+}
+
+// SafeBounds returns the safe area in dots.
+func (p Page) SafeBounds(drawer drawing.Drawer) image.Rectangle {
+	x, y, w, h := p.SafeArea()
+	return image.Rect(drawer.ToDot(x), drawer.ToDot(y), drawer.ToDot(x+w), drawer.ToDot(y+h))
+}
+
+func (p Page) Check() error {
+	if p.Width <= 0 || p.Height <= 0 {
+		return fmt.Errorf("Invalid page size %.2fx%.2fmm", p.Width, p.Height)
+	}
+	if p.Bleed < 0 || p.Safe < 0 {
+		return fmt.Errorf("Bleed and safe margin cannot be negative")
+	}
+	if _, _, w, h := p.SafeArea(); w <= 0 || h <= 0 {
+		return fmt.Errorf("Margins leave no safe area on a %.2fx%.2fmm page", p.Width, p.Height)
+	}
+	return nil
+}
+
+// drawCropMarks marks the corners of the trimmed page in the bleed.
+func (p Page) drawCropMarks(drawer drawing.Drawer) {
+	bleed := drawer.ToDot(p.Bleed)
+	offset := bleed / 2
+	length := bleed - offset
+	thickness := max(1, drawer.ToDot(0.1))
+	width := drawer.ToDot(p.Width)
+	height := drawer.ToDot(p.Height)
+	drawer.BeginLayer(drawing.GuideLayer)
+	defer drawer.EndLayer()
+	for _, x := range []int{bleed, bleed + width} {
+		drawer.DrawRectangle(x-thickness/2, 0, thickness, length, color.Black)
+		drawer.DrawRectangle(x-thickness/2, bleed+height+offset, thickness, length, color.Black)
+	}
+	for _, y := range []int{bleed, bleed + height} {
+		drawer.DrawRectangle(0, y-thickness/2, length, thickness, color.Black)
+		drawer.DrawRectangle(bleed+width+offset, y-thickness/2, length, thickness, color.Black)
+	}
+}
+
+// Draw lays families out with layouter on the trimmed page, drawer
+// being the size of the canvas. With a bleed, the bleed is filled
+// with white and the trim corners are marked.
+func (p Page) Draw(drawer drawing.Drawer, layouter Layouter, families []FamilyBlock) error {
+	if p.Bleed <= 0 {
+		return layouter.Layout(drawer, families)
+	}
+	bleed := drawer.ToDot(p.Bleed)
+	width := drawer.ToDot(p.CanvasWidth())
+	height := drawer.ToDot(p.CanvasHeight())
+	drawer.DrawRectangle(0, 0, width, bleed, color.White)
+	drawer.DrawRectangle(0, height-bleed, width, bleed, color.White)
+	drawer.DrawRectangle(0, bleed, bleed, height-2*bleed, color.White)
+	drawer.DrawRectangle(width-bleed, bleed, bleed, height-2*bleed, color.White)
+	p.drawCropMarks(drawer)
+
+	drawer.RotateTranslate(bleed, bleed, 0.0)
+	defer drawer.EndRotateTranslate()
+	return layouter.Layout(drawer, families)
+}
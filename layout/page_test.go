@@ -0,0 +1,61 @@
+package layout
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type PageSuite struct{}
+
+var _ = Suite(&PageSuite{})
+
+func (s *PageSuite) TestExtractMargins(c *C) {
+	testdata := []struct {
+		Input    string
+		Expected Margins
+	}{
+		{"5", Margins{5, 5, 5, 5}},
+		{"5,3", Margins{5, 3, 5, 3}},
+		{"5, 3, 12", Margins{5, 3, 12, 3}},
+		{"5,3,12,4.5", Margins{5, 3, 12, 4.5}},
+	}
+	for _, d := range testdata {
+		res, err := ExtractMargins(d.Input)
+		if c.Check(err, IsNil, Commentf("for %s", d.Input)) == false {
+			continue
+		}
+		c.Check(res, Equals, d.Expected, Commentf("for %s", d.Input))
+	}
+
+	errdata := map[string]string{
+		"1,2,3,4,5": "Invalid margins '1,2,3,4,5': expected 1 to 4 values",
+		"1,a":       "Invalid margins '1,a': .*invalid syntax",
+		"-1":        "Invalid margins '-1': margins cannot be negative",
+	}
+	for input, expected := range errdata {
+		_, err := ExtractMargins(input)
+		c.Check(err, ErrorMatches, expected)
+	}
+}
+
+func (s *PageSuite) TestSafeArea(c *C) {
+	p := Page{
+		Width:   210,
+		Height:  297,
+		Bleed:   3,
+		Margins: Margins{Top: 5, Right: 4, Bottom: 12, Left: 6},
+		Safe:    1,
+	}
+	c.Check(p.CanvasWidth(), Equals, 216.0)
+	c.Check(p.CanvasHeight(), Equals, 303.0)
+	x, y, w, h := p.SafeArea()
+	c.Check([]float64{x, y, w, h}, DeepEquals, []float64{7, 6, 198, 278})
+	c.Check(p.Check(), IsNil)
+
+	p.Margins = UniformMargins(105)
+	c.Check(p.Check(), ErrorMatches, "Margins leave no safe area on a 210.00x297.00mm page")
+}
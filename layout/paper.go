@@ -0,0 +1,62 @@
+package layout
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PaperSize is a paper format in mm.
+type PaperSize struct {
+	Width, Height float64
+}
+
+var PaperPresets = map[string]PaperSize{
+	"a3":      {297, 420},
+	"a4":      {210, 297},
+	"a5":      {148, 210},
+	"a6":      {105, 148},
+	"b4":      {250, 353},
+	"b5":      {176, 250},
+	"sra3":    {320, 450},
+	"sra4":    {225, 320},
+	"letter":  {215.9, 279.4},
+	"legal":   {215.9, 355.6},
+	"tabloid": {279.4, 431.8},
+}
+
+func paperPresetNames() []string {
+	res := make([]string, 0, len(PaperPresets))
+	for name := range PaperPresets {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+var customPaperRx = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?)x([0-9]+(?:\.[0-9]*)?)$`)
+
+// ExtractPaperSize parses either a preset name, case insensitive, or
+// a custom '<width>x<height>' size in mm. Presets are in portrait
+// orientation and custom sizes as given, unless landscape is set.
+func ExtractPaperSize(s string, landscape bool) (PaperSize, error) {
+	res, ok := PaperPresets[strings.ToLower(s)]
+	if ok == false {
+		m := customPaperRx.FindStringSubmatch(s)
+		if m == nil {
+			return res, fmt.Errorf("Unknown paper size '%s', expected '<width>x<height>' or one of %s", s, strings.Join(paperPresetNames(), ", "))
+		}
+		// the regexp ensures that these are valid floats
+		res.Width, _ = strconv.ParseFloat(m[1], 64)
+		res.Height, _ = strconv.ParseFloat(m[2], 64)
+		if res.Width <= 0 || res.Height <= 0 {
+			return res, fmt.Errorf("Invalid paper size '%s': dimensions must be strictly positive", s)
+		}
+	}
+	if landscape == true && res.Width < res.Height {
+		res.Width, res.Height = res.Height, res.Width
+	}
+	return res, nil
+}
@@ -0,0 +1,36 @@
+package layout
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type PaperSuite struct{}
+
+var _ = Suite(&PaperSuite{})
+
+func (s *PaperSuite) TestExtractPaperSize(c *C) {
+	testdata := []struct {
+		Input     string
+		Landscape bool
+		Expected  PaperSize
+	}{
+		{"a4", false, PaperSize{210, 297}},
+		{"A4", true, PaperSize{297, 210}},
+		{"letter", false, PaperSize{215.9, 279.4}},
+		{"200.02x138.5", false, PaperSize{200.02, 138.5}},
+		{"50x80", false, PaperSize{50, 80}},
+		{"50x80", true, PaperSize{80, 50}},
+	}
+	for _, d := range testdata {
+		res, err := ExtractPaperSize(d.Input, d.Landscape)
+		if c.Check(err, IsNil, Commentf("for %s", d.Input)) == false {
+			continue
+		}
+		c.Check(res, Equals, d.Expected, Commentf("for %s", d.Input))
+	}
+
+	_, err := ExtractPaperSize("a10", false)
+	c.Check(err, ErrorMatches, "Unknown paper size 'a10', expected '<width>x<height>' or one of a3, a4, .*")
+	_, err = ExtractPaperSize("0x10", false)
+	c.Check(err, ErrorMatches, "Invalid paper size '0x10': dimensions must be strictly positive")
+}
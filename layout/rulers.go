@@ -0,0 +1,109 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+const (
+	rulerWidth = 5.0
+	rulerGap   = 1.0
+	// ReferenceSquareSize is the edge length in mm of the reference
+	// square drawn where the rulers meet.
+	ReferenceSquareSize = rulerWidth
+)
+
+// ReserveRulers returns p with its top and left margins extended to
+// leave room for the rulers.
+func ReserveRulers(p Page) Page {
+	p.Margins.Top += rulerWidth + rulerGap
+	p.Margins.Left += rulerWidth + rulerGap
+	return p
+}
+
+// RulerLayouter lays families out with Inner, then draws millimeter
+// rulers along the top and left edges of the safe area of Page, and a
+// reference square in their corner. Inner should lay out in the page
+// returned by ReserveRulers.
+type RulerLayouter struct {
+	Inner Layouter
+	Page  Page
+}
+
+type rulerTick struct {
+	Every  int // in tenth of mm
+	Length float64
+}
+
+var rulerTicks = []rulerTick{
+	{Every: 100, Length: rulerWidth},
+	{Every: 50, Length: 0.6 * rulerWidth},
+	{Every: 10, Length: 0.4 * rulerWidth},
+	{Every: 1, Length: 0.2 * rulerWidth},
+}
+
+// tickLength returns the length of the tick at i tenth of mm, 0 if
+// there is none.
+func tickLength(i int, fine bool) float64 {
+	for _, t := range rulerTicks {
+		if t.Every == 1 && fine == false {
+			return 0.0
+		}
+		if i%t.Every == 0 {
+			return t.Length
+		}
+	}
+	return 0.0
+}
+
+// drawRuler draws a ruler of length mm starting at (x,y) in dots, with
+// its ticks going down, or right if vertical.
+func (l *RulerLayouter) drawRuler(drawer drawing.Drawer, x, y int, length float64, vertical bool) {
+	tenth := drawer.ToDot(0.1)
+	// fine ticks need at least one blank dot between them
+	fine := tenth >= 2
+	thickness := max(1, tenth/2)
+	labelHeight := drawer.ToDot(0.3 * rulerWidth)
+	for i := 0; float64(i) <= 10*length; i++ {
+		tl := tickLength(i, fine)
+		if tl == 0.0 {
+			continue
+		}
+		pos := drawer.ToDot(float64(i) / 10)
+		if vertical == true {
+			drawer.DrawRectangle(x, y+pos, drawer.ToDot(tl), thickness, color.Black)
+		} else {
+			drawer.DrawRectangle(x+pos, y, thickness, drawer.ToDot(tl), color.Black)
+		}
+		if i%100 != 0 || i == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%d", i/10)
+		if vertical == true {
+			drawer.Label(x+drawer.ToDot(0.45*rulerWidth), y+pos+thickness, labelHeight, label, color.Black)
+		} else {
+			drawer.Label(x+pos+2*thickness, y+drawer.ToDot(0.65*rulerWidth), labelHeight, label, color.Black)
+		}
+	}
+}
+
+func (l *RulerLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	if err := l.Inner.Layout(drawer, families); err != nil {
+		return err
+	}
+	safeX, safeY, safeWidth, safeHeight := l.Page.SafeArea()
+	offset := rulerWidth + rulerGap
+	if safeWidth <= offset || safeHeight <= offset {
+		return fmt.Errorf("Safe area is too small for the rulers")
+	}
+	x := drawer.ToDot(safeX)
+	y := drawer.ToDot(safeY)
+	drawer.BeginLayer(drawing.GuideLayer)
+	defer drawer.EndLayer()
+	drawer.DrawRectangle(x, y, drawer.ToDot(ReferenceSquareSize), drawer.ToDot(ReferenceSquareSize), color.Black)
+	l.drawRuler(drawer, drawer.ToDot(safeX+offset), y, safeWidth-offset, false)
+	l.drawRuler(drawer, x, drawer.ToDot(safeY+offset), safeHeight-offset, true)
+	return nil
+}
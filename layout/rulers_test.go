@@ -0,0 +1,21 @@
+package layout
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type RulersSuite struct{}
+
+var _ = Suite(&RulersSuite{})
+
+func (s *RulersSuite) TestRulerTicks(c *C) {
+	c.Check(tickLength(0, true), Equals, rulerWidth)
+	c.Check(tickLength(100, false), Equals, rulerWidth)
+	c.Check(tickLength(50, false), Equals, 0.6*rulerWidth)
+	c.Check(tickLength(30, false), Equals, 0.4*rulerWidth)
+	c.Check(tickLength(31, true), Equals, 0.2*rulerWidth)
+	c.Check(tickLength(31, false), Equals, 0.0)
+
+	p := ReserveRulers(Page{Width: 100, Height: 100, Margins: UniformMargins(5)})
+	c.Check(p.Margins, Equals, Margins{Top: 11, Right: 5, Bottom: 5, Left: 11})
+}
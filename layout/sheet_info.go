@@ -0,0 +1,96 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"rsc.io/qr"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+)
+
+const (
+	sheetInfoHeight = 10.0
+	sheetInfoGap    = 2.0
+)
+
+// ReserveSheetInfo returns p with its top margin, or bottom margin for
+// a footer, extended to leave room for the sheet info strip.
+func ReserveSheetInfo(p Page, footer bool) Page {
+	if footer == true {
+		p.Margins.Bottom += sheetInfoHeight + sheetInfoGap
+	} else {
+		p.Margins.Top += sheetInfoHeight + sheetInfoGap
+	}
+	return p
+}
+
+// SheetInfoLayouter lays families out with Inner, then draws a strip
+// with the job metadata and a QR code of the manifest ID at the top,
+// or at the bottom with Footer, of the safe area of Page. Inner should
+// lay out in the page returned by ReserveSheetInfo.
+type SheetInfoLayouter struct {
+	Inner    Layouter
+	Page     Page
+	Manifest *Manifest
+	Footer   bool
+}
+
+func (l *SheetInfoLayouter) lines() []string {
+	m := l.Manifest
+	res := []string{
+		fmt.Sprintf("%s  %s  %d DPI  TAG-LAYOUTER %s  ID %s", m.Job, m.Date.Format("2006-01-02 15:04 MST"), m.DPI, m.Version, m.ID),
+	}
+	families := make([]string, 0, len(m.Families))
+	for _, f := range m.Families {
+		desc := fmt.Sprintf("%s %.2fMM (%.2fMM) %s", strings.ToUpper(f.Family), f.Size, f.ActualSize, f.Ranges)
+		families = append(families, desc)
+	}
+	res = append(res, strings.Join(families, ", "))
+	res = append(res, fmt.Sprintf("%s  %.2fX%.2fMM", m.Output, m.Page.Width, m.Page.Height))
+	return res
+}
+
+func (l *SheetInfoLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	if err := l.Inner.Layout(drawer, families); err != nil {
+		return err
+	}
+
+	code, err := qr.Encode(l.Manifest.ID, qr.M)
+	if err != nil {
+		return err
+	}
+	safeX, safeY, safeWidth, safeHeight := l.Page.SafeArea()
+	if l.Footer == true {
+		safeY += safeHeight - sheetInfoHeight
+	}
+	x := drawer.ToDot(safeX)
+	y := drawer.ToDot(safeY)
+	width := drawer.ToDot(safeWidth)
+	height := drawer.ToDot(sheetInfoHeight)
+
+	module := height / code.Size
+	if module < 1 {
+		return fmt.Errorf("DPI is too low to draw the sheet info QR code")
+	}
+	drawer.BeginLayer(drawing.InfoLayer)
+	defer drawer.EndLayer()
+	drawer.DrawRectangle(x, y, width, height, color.White)
+	for j := 0; j < code.Size; j++ {
+		for i := 0; i < code.Size; i++ {
+			if code.Black(i, j) == true {
+				drawer.DrawRectangle(x+i*module, y+j*module, module, module, color.Black)
+			}
+		}
+	}
+	// text starts after the quiet zone of the QR code
+	textX := x + (code.Size+4)*module
+
+	lines := l.lines()
+	lineHeight := height / len(lines)
+	for i, line := range lines {
+		drawer.Label(textX, y+i*lineHeight, lineHeight*4/5, line, color.Black)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package layout
+
+import (
+	"image"
+	"time"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type SheetInfoSuite struct {
+	Page     Page
+	Manifest *Manifest
+}
+
+var _ = Suite(&SheetInfoSuite{})
+
+type nopLayouter struct {
+	called bool
+}
+
+func (l *nopLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	l.called = true
+	return nil
+}
+
+func (s *SheetInfoSuite) SetUpTest(c *C) {
+	s.Page = Page{Width: 100, Height: 150, Margins: UniformMargins(10)}
+	tf := &families.TagFamily{Name: "TEST", TotalWidth: 10, Codes: make([]uint64, 10)}
+	blocks := []FamilyBlock{{Family: tf, Size: 1, Ranges: []ranges.Range{{Begin: 0, End: 10}}}}
+	s.Manifest = NewManifest("job", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), 254, "out.png", s.Page, blocks)
+}
+
+// infoRectangles returns the rectangles drawn on the InfoLayer.
+func infoRectangles(d *recordingDrawer) []image.Rectangle {
+	res := []image.Rectangle{}
+	for _, r := range d.Rectangles {
+		if r.Layer == drawing.InfoLayer {
+			res = append(res, r.Bounds)
+		}
+	}
+	return res
+}
+
+func (s *SheetInfoSuite) TestReservesTheInfoArea(c *C) {
+	header := ReserveSheetInfo(s.Page, false)
+	c.Check(header.Margins, Equals, Margins{Top: 22, Right: 10, Bottom: 10, Left: 10})
+	footer := ReserveSheetInfo(s.Page, true)
+	c.Check(footer.Margins, Equals, Margins{Top: 10, Right: 10, Bottom: 22, Left: 10})
+
+	// at 10 dots per mm, the strip is 10mm high within the safe area,
+	// and ends 2mm before the area left to the inner layouter
+	testdata := []struct {
+		Footer   bool
+		Reserved Page
+		Strip    image.Rectangle
+	}{
+		{false, header, image.Rect(100, 100, 900, 200)},
+		{true, footer, image.Rect(100, 1300, 900, 1400)},
+	}
+	for _, d := range testdata {
+		inner := &nopLayouter{}
+		l := &SheetInfoLayouter{Inner: inner, Page: s.Page, Manifest: s.Manifest, Footer: d.Footer}
+		drawer := newRecordingDrawer(254)
+		c.Assert(l.Layout(drawer, nil), IsNil)
+		c.Check(inner.called, Equals, true)
+		rects := infoRectangles(drawer)
+		c.Assert(len(rects) > 1, Equals, true)
+		c.Check(rects[0], Equals, d.Strip)
+		for _, r := range rects {
+			c.Check(r.In(d.Strip), Equals, true, Commentf("%v is outside of the strip %v", r, d.Strip))
+		}
+		_, y, _, height := d.Reserved.SafeArea()
+		reserved := image.Rect(100, drawer.ToDot(y), 900, drawer.ToDot(y+height))
+		c.Check(reserved.Overlaps(d.Strip), Equals, false)
+		c.Check(drawer.Labels, HasLen, 3)
+		c.Check(drawer.Labels[0], Matches, "job  2020-01-02 03:04 UTC  254 DPI .* ID "+s.Manifest.ID)
+	}
+}
+
+func (s *SheetInfoSuite) TestNeedsAQRModuleOfADot(c *C) {
+	l := &SheetInfoLayouter{Inner: &nopLayouter{}, Page: s.Page, Manifest: s.Manifest}
+	// 10mm at 50 DPI are fewer dots than the 21 modules of the code
+	err := l.Layout(newRecordingDrawer(50), nil)
+	c.Check(err, ErrorMatches, "DPI is too low to draw the sheet info QR code")
+}
@@ -0,0 +1,112 @@
+package layout
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+// StripLayouter lays families one after the other along a roll of
+// fixed Width and unbounded length. Families are split in segments of
+// CutEvery tags, each with its own label, and a cut mark is drawn
+// across the roll between two segments. The left and right margins of
+// the Page are used on the sides, the top and bottom ones at both ends
+// of the strip, and FamilyMargin between segments. The Page height is
+// set from the content.
+type StripLayouter struct {
+	ColumnLayouter
+	CutEvery int
+
+	cuts []int
+}
+
+// place returns the segments and the length of the strip in dots.
+func (s *StripLayouter) place(families []FamilyBlock) ([]PlacedFamily, int, error) {
+	if s.CutEvery < 0 {
+		return nil, 0, fmt.Errorf("Number of tags between cut marks cannot be negative")
+	}
+	safeX, safeY, safeWidth, _ := s.Page.SafeArea()
+	if safeWidth <= 0 {
+		return nil, 0, fmt.Errorf("Margins leave no safe area on a %.2fmm wide strip", s.Page.Width)
+	}
+	margin := s.drawer.ToDot(s.FamilyMargin)
+	x := s.drawer.ToDot(safeX)
+	width := s.drawer.ToDot(safeX+safeWidth) - x
+
+	s.cuts = nil
+	res := []PlacedFamily{}
+	y := s.drawer.ToDot(safeY)
+	for _, f := range families {
+		ids := ranges.IDsOfRanges(f.Ranges)
+		segmentSize := s.CutEvery
+		if segmentSize == 0 {
+			segmentSize = len(ids)
+		}
+		for start := 0; start < len(ids); start += segmentSize {
+			segment := f
+			segment.Ranges = ranges.RangesOfIDs(ids[start:min(start+segmentSize, len(ids))])
+			pf := s.ComputeFamilySize(segment, width)
+			if pf.NTagsPerRow < 1 {
+				return nil, 0, fmt.Errorf("Strip is too narrow for %s:%.2f", f.Family.Name, f.Size)
+			}
+			if len(res) > 0 {
+				s.cuts = append(s.cuts, y-margin/2)
+			}
+			pf.X = x
+			pf.Y = y
+			y += pf.Height + margin
+			res = append(res, pf)
+		}
+	}
+	if len(res) == 0 {
+		return nil, 0, fmt.Errorf("No tag to lay out")
+	}
+	return res, y - margin + s.drawer.ToDot(s.Page.Margins.Bottom+s.Page.Safe), nil
+}
+
+// ContentHeight returns the length in mm of the strip needed by
+// families.
+func (s *StripLayouter) ContentHeight(DPI int, families []FamilyBlock) (float64, error) {
+	s.drawer = drawing.NewMeasureDrawer(DPI)
+	_, length, err := s.place(families)
+	if err != nil {
+		return 0.0, err
+	}
+	// rounds up to not lose the last dot
+	return s.drawer.ToMM(length + 1), nil
+}
+
+// drawCutMark draws a dashed line across the strip, centered on y.
+func (s *StripLayouter) drawCutMark(y int) {
+	thickness := max(1, s.drawer.ToDot(0.1))
+	y -= thickness / 2
+	dash := max(1, s.drawer.ToDot(1.0))
+	width := s.drawer.ToDot(s.Page.Width)
+	s.drawer.BeginLayer(drawing.CutLayer)
+	defer s.drawer.EndLayer()
+	for x := 0; x < width; x += 2 * dash {
+		s.drawer.DrawRectangle(x, y, min(dash, width-x), thickness, color.Black)
+	}
+}
+
+func (s *StripLayouter) Layout(drawer drawing.Drawer, families []FamilyBlock) error {
+	s.drawer = drawer
+	placed, length, err := s.place(families)
+	if err != nil {
+		return err
+	}
+	s.Page.Height = drawer.ToMM(length + 1)
+	log.Printf("Strip length: %.2fmm, %d segments", s.Page.Height, len(placed))
+
+	s.fillBackground()
+	for _, pf := range placed {
+		s.LayoutOne(pf)
+	}
+	for _, y := range s.cuts {
+		s.drawCutMark(y)
+	}
+	return nil
+}
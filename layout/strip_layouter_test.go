@@ -0,0 +1,65 @@
+package layout
+
+import (
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+	. "gopkg.in/check.v1"
+)
+
+type StripLayouterSuite struct {
+	Family *families.TagFamily
+}
+
+var _ = Suite(&StripLayouterSuite{})
+
+func (s *StripLayouterSuite) SetUpSuite(c *C) {
+	s.Family = &families.TagFamily{Name: "TEST", TotalWidth: 8, Codes: make([]uint64, 100)}
+}
+
+func (s *StripLayouterSuite) layouter(cutEvery int) *StripLayouter {
+	return &StripLayouter{
+		ColumnLayouter: ColumnLayouter{
+			Page:         Page{Width: 40, Margins: UniformMargins(2)},
+			FamilyMargin: 2,
+			TagBorder:    0.2,
+		},
+		CutEvery: cutEvery,
+	}
+}
+
+func (s *StripLayouterSuite) TestSplitsInSegments(c *C) {
+	families := []FamilyBlock{{Family: s.Family, Size: 3, Ranges: []ranges.Range{{Begin: 0, End: 30}}}}
+	l := s.layouter(12)
+	l.drawer = drawing.NewMeasureDrawer(300)
+	placed, length, err := l.place(families)
+	c.Assert(err, IsNil)
+	c.Assert(placed, HasLen, 3)
+	c.Check(l.cuts, HasLen, 2)
+	expected := [][]ranges.Range{{{Begin: 0, End: 12}}, {{Begin: 12, End: 24}}, {{Begin: 24, End: 30}}}
+	for i, pf := range placed {
+		c.Check(pf.Ranges, DeepEquals, expected[i])
+		if i > 0 {
+			c.Check(pf.Y > placed[i-1].Y+placed[i-1].Height, Equals, true)
+			c.Check(l.cuts[i-1] > placed[i-1].Y+placed[i-1].Height && l.cuts[i-1] < pf.Y, Equals, true)
+		}
+	}
+	last := placed[len(placed)-1]
+	c.Check(length, Equals, last.Y+last.Height+l.drawer.ToDot(2))
+}
+
+func (s *StripLayouterSuite) TestContentHeight(c *C) {
+	families := []FamilyBlock{{Family: s.Family, Size: 3, Ranges: []ranges.Range{{Begin: 0, End: 30}}}}
+	single, err := s.layouter(0).ContentHeight(300, families)
+	c.Assert(err, IsNil)
+	split, err := s.layouter(10).ContentHeight(300, families)
+	c.Assert(err, IsNil)
+	c.Check(split > single, Equals, true)
+
+	_, err = s.layouter(-1).ContentHeight(300, families)
+	c.Check(err, ErrorMatches, "Number of tags between cut marks cannot be negative")
+
+	families[0].Size = 50
+	_, err = s.layouter(0).ContentHeight(300, families)
+	c.Check(err, ErrorMatches, "Strip is too narrow for TEST:50.00")
+}
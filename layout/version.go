@@ -0,0 +1,5 @@
+package layout
+
+// Version of tag-layouter recorded in the manifests, set at build
+// time with -ldflags "-X github.com/formicidae-tracker/tag-layouter/layout.Version=<version>".
+var Version = "development"
@@ -1,5 +0,0 @@
-package main
-
-type Layouter interface {
-	Layout(drawer Drawer, families []FamilyBlock) error
-}
@@ -1,164 +1,388 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"math"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/layout"
 	"github.com/jessevdk/go-flags"
 )
 
-type Point struct {
-	X, Y float64
-}
-
-func Touches(points map[int]Point, toTest Point, radius float64) bool {
-	for _, p := range points {
-		distX := p.X - toTest.X
-		distY := p.Y - toTest.Y
-		dist := math.Sqrt(distX*distX + distY*distY)
-		if dist < radius {
-			return true
-		}
-	}
-	return false
-}
-
 type Options struct {
-	File             string   `short:"f" long:"file" description:"File to output" required:"true"`
-	FamilyAndSize    []string `short:"t" long:"family-and-size" description:"Families and size to use. format: 'name:size:begin-end'"`
-	ColumnNumber     int      `long:"column-number" description:"Number of column to display multiple families" default:"0"`
+	File             string   `short:"f" long:"file" description:"File to output, required without a command"`
+	FamilyAndSize    []string `short:"t" long:"family-and-size" description:"Families and size to use. format: 'name:size:range', see README for the range syntax"`
+	ColumnNumber     int      `long:"column-number" description:"Number of column to display multiple families, maximal number with --auto-columns" default:"0"`
+	AutoColumns      bool     `long:"auto-columns" description:"Chooses the number of columns that uses the least page height"`
+	AdaptiveColumns  bool     `long:"adaptive-column-width" description:"Sizes each column to the families it contains"`
 	TagBorder        float64  `long:"individual-tag-border" description:"border between tags in column layout" default:"0.2"`
 	CutLineRatio     float64  `long:"cut-line-ratio" description:"ratio of the border between tags that should be a cut line" default:"0.0"`
 	FamilyMargin     float64  `long:"family-margin" description:"margin between families in mm" default:"2.0"`
 	ArenaNumber      int      `long:"arena-number" description:"Number of tags to display in an arena" default:"0"`
 	Width            float64  `short:"W" long:"width" description:"Width to use" default:"210"`
 	Height           float64  `short:"H" long:"height" description:"Height to use" default:"297"`
-	PaperBorder      float64  `long:"paper-border" description:"Border for arena or paper" default:"20.0"`
+	PaperBorder      float64  `long:"paper-border" description:"Margin on all the sides of the paper, see --margins" default:"20.0"`
+	Margins          string   `long:"margins" description:"Non printable margins in mm as 'top,right,bottom,left', overrides --paper-border"`
+	Bleed            float64  `long:"bleed" description:"Bleed added around the page in mm" default:"0.0"`
+	SafeMargin       float64  `long:"safe-margin" description:"Distance between the margins and the tags in mm" default:"0.0"`
 	LabelRoundedSize bool     `long:"label-rounded-size" description:"Label the rounded size instead of the actual size"`
 	DPI              int      `short:"d" long:"dpi" description:"DPI to use" default:"2400"`
+	Packing          string   `long:"packing" description:"Packing strategy for column layout" default:"column"`
+	ReflowBlocks     bool     `long:"reflow-blocks" description:"Allows the packing strategy to reflow the tags of a family block in a grid as wide as the block is tall"`
+	FillFrom         int      `long:"fill-from" description:"Fills the page with consecutive IDs of a single family starting at this ID" default:"-1"`
+	Strip            bool     `long:"strip" description:"Lays the families along a roll of the given width, the height is computed from the content"`
+	CutEvery         int      `long:"cut-every" description:"Number of tags between two cut marks in strip layout, 0 to cut only between families" default:"0"`
+	Paper            string   `short:"p" long:"paper" description:"Paper size preset (a3, a4, letter, ...) or '<width>x<height>' in mm, overrides --width and --height"`
+	Landscape        bool     `long:"landscape" description:"Uses the paper in landscape orientation"`
+	Impose           string   `long:"impose" description:"Lays out a block of '<width>x<height>' mm and repeats it across the sheet"`
+	ImposeCount      int      `long:"impose-count" description:"Maximal number of blocks to impose, 0 for as many as fit" default:"0"`
+	Gutter           float64  `long:"gutter" description:"Space between two imposed blocks in mm" default:"5.0"`
+	SheetInfo        string   `long:"sheet-info" description:"Draws the job metadata and a QR code of the manifest ID as a header or a footer" choice:"none" choice:"header" choice:"footer" default:"none"`
+	Rulers           bool     `long:"rulers" description:"Draws mm rulers and a reference square along the top and left edges to check the print scale"`
+	Job              string   `long:"job" description:"Job name for the sheet info and the manifest, defaults to the output file name"`
+	Manifest         string   `long:"manifest" description:"Writes the job manifest to this file, defaults to the output file with a .json extension when --sheet-info is used"`
+	Bilevel          bool     `long:"bilevel" description:"Stores 1 bit per pixel, written as a CCITT Group 4 TIFF or a 1 bit PNG"`
+	RGB              bool     `long:"rgb" description:"Stores 3 bytes per pixel, to keep the colors of the labels"`
+	Separate         string   `long:"separate" description:"Comma separated layers drawn in their own image with the layer name as suffix, among labels, cuts, guides and info"`
+	LabelLayer       bool     `long:"label-layer" description:"Draws the labels in a separate image with a _labels suffix, same as --separate=labels"`
+	Jobs             int      `short:"j" long:"jobs" description:"Number of bands of raster outputs rendered concurrently, 0 for the number of CPUs" default:"0"`
+	EngraveSpacing   float64  `long:"engrave-spacing" description:"Distance between two scan lines of engraving outputs in mm" default:"0.05"`
+	EngraveSpeed     float64  `long:"engrave-speed" description:"Feed rate of the engraving moves of G-code outputs in mm/min" default:"1000"`
+	EngravePower     float64  `long:"engrave-power" description:"Spindle or laser power of the engraving moves of G-code outputs" default:"1000"`
+	EngraveFill      string   `long:"engrave-fill" description:"Fills the black areas of DXF outputs with closed polygons or scan lines" choice:"polygons" choice:"lines" default:"polygons"`
+}
+
+// vectorDrawers create the drawers of vector outputs, which keep the
+// layers in a single file.
+var vectorDrawers = map[string]func(filepath string, width, height float64, DPI int) (drawing.Drawer, error){
+	".svg": drawing.NewSVGDrawer,
+	".pdf": drawing.NewPDFDrawer,
+	".ps":  drawing.NewPSDrawer,
+	".eps": drawing.NewPSDrawer,
+}
+
+func (opts *Options) imageOptions() (drawing.ImageOptions, error) {
+	res := drawing.ImageOptions{Mode: drawing.GrayMode, Jobs: opts.Jobs}
+	if opts.Bilevel == true && opts.RGB == true {
+		return res, fmt.Errorf("--bilevel and --rgb are mutually exclusive")
+	}
+	if opts.Bilevel == true {
+		res.Mode = drawing.BilevelMode
+	} else if opts.RGB == true {
+		res.Mode = drawing.RGBMode
+	}
+	var err error
+	res.Separate, err = drawing.ExtractLayers(opts.Separate)
+	if err != nil {
+		return res, err
+	}
+	if opts.LabelLayer == true && drawing.ContainsLayer(res.Separate, drawing.LabelLayer) == false {
+		res.Separate = append(res.Separate, drawing.LabelLayer)
+	}
+	return res, nil
 }
 
-func ExtractFamilyAndSizes(list []string) ([]FamilyBlock, error) {
-	res := []FamilyBlock{}
-	for _, fAndSize := range list {
-		fargs := strings.Split(fAndSize, ":")
-		if len(fargs) <= 1 {
-			return res, fmt.Errorf("invalid family specification '%s': need at list family and size in the form '<name>:<size>'", fAndSize)
+// invalidJobError is an error caused by the options of a job, rather
+// than by the host generating it.
+type invalidJobError struct {
+	error
+}
+
+// sheet is a validated job, ready to be drawn.
+type sheet struct {
+	page         layout.Page
+	file         string
+	layouter     layout.Layouter
+	blocks       []layout.FamilyBlock
+	manifest     *layout.Manifest
+	manifestPath string
+	newDrawer    func() (drawing.Drawer, error)
+}
+
+// Generate lays out the sheet described by the options, and fails if
+// it exceeds the limits. Invalid options and layouts are reported as
+// invalidJobError. Once ctx is done, the generation stops and fails
+// with its error, without leaving any output.
+func (opts *Options) Generate(ctx context.Context, limits Limits) error {
+	s, err := opts.sheet(ctx, limits)
+	if err != nil {
+		return invalidJobError{err}
+	}
+	return s.draw(ctx)
+}
+
+// sheet validates the options against limits, and prepares the sheet
+// they describe without writing anything.
+func (opts *Options) sheet(ctx context.Context, limits Limits) (*sheet, error) {
+	if len(opts.File) == 0 {
+		return nil, fmt.Errorf("the required flag `-f, --file' was not specified")
+	}
+	blocks, err := layout.ExtractFamilyAndSizes(opts.FamilyAndSize)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range blocks {
+		if b.Filtered == true {
+			log.Printf("%s: %d tags match the filters", opts.FamilyAndSize[i], b.NumberOfTags())
 		}
-		if len(fargs) > 3 {
-			return res, fmt.Errorf("invalid family specification '%s':  expected '<name>:<size>:<range>'", fAndSize)
+	}
+	imageOpts, err := opts.imageOptions()
+	if err != nil {
+		return nil, err
+	}
+	imageOpts.Context = ctx
+
+	page := layout.Page{
+		Width:   opts.Width,
+		Height:  opts.Height,
+		Bleed:   opts.Bleed,
+		Margins: layout.UniformMargins(opts.PaperBorder),
+		Safe:    opts.SafeMargin,
+	}
+	if len(opts.Paper) > 0 {
+		paper, err := layout.ExtractPaperSize(opts.Paper, opts.Landscape)
+		if err != nil {
+			return nil, err
 		}
-		tf, err := GetFamily(fargs[0])
+		page.Width, page.Height = paper.Width, paper.Height
+	} else if opts.Landscape == true && page.Width < page.Height {
+		page.Width, page.Height = page.Height, page.Width
+	}
+	if len(opts.Margins) > 0 {
+		page.Margins, err = layout.ExtractMargins(opts.Margins)
 		if err != nil {
-			return res, err
+			return nil, err
+		}
+	}
+
+	// the area left once the sheet info is reserved is laid out
+	layoutPage := page
+	if opts.SheetInfo != "none" {
+		layoutPage = layout.ReserveSheetInfo(page, opts.SheetInfo == "footer")
+	}
+	rulerPage := layoutPage
+	if opts.Rulers == true {
+		layoutPage = layout.ReserveRulers(rulerPage)
+	}
+
+	// without imposition the block is the whole page, otherwise blocks
+	// have no margins and are placed in the safe area of the page.
+	block := layoutPage
+	if len(opts.Impose) > 0 {
+		if opts.Strip == true {
+			return nil, fmt.Errorf("--impose cannot be used with --strip")
 		}
-		s, err := strconv.ParseFloat(fargs[1], 64)
+		size, err := layout.ExtractPaperSize(opts.Impose, false)
 		if err != nil {
-			return res, err
+			return nil, err
 		}
+		block = layout.Page{Width: size.Width, Height: size.Height}
+	}
 
-		if len(fargs) == 2 {
-			res = append(res, FamilyBlock{
-				Family: tf,
-				Size:   s,
-				Ranges: []Range{
-					Range{
-						Begin: 0,
-						End:   len(tf.Codes),
-					},
-				},
-			})
-			continue
+	var layouter layout.Layouter = nil
+
+	columnLayout := opts.ColumnNumber != 0 || opts.AutoColumns == true
+	nLayouts := 0
+	for _, selected := range []bool{opts.ArenaNumber != 0, columnLayout, opts.FillFrom >= 0, opts.Strip} {
+		if selected == true {
+			nLayouts++
 		}
+	}
+	if nLayouts > 1 {
+		return nil, fmt.Errorf("Please specify only one of --arena-number, --column-number, --fill-from or --strip")
+	}
 
-		ranges, err := ExtractRanges(fargs[2])
-		if err != nil {
-			return res, err
+	columns := layout.ColumnLayouter{
+		Page:                block,
+		NColumns:            opts.ColumnNumber,
+		FamilyMargin:        opts.FamilyMargin,
+		TagBorder:           opts.TagBorder,
+		LabelroundedSize:    opts.LabelRoundedSize,
+		CutLine:             opts.CutLineRatio,
+		Packing:             opts.Packing,
+		ReflowBlocks:        opts.ReflowBlocks,
+		AutoColumns:         opts.AutoColumns,
+		AdaptiveColumnWidth: opts.AdaptiveColumns,
+	}
+
+	switch {
+	case opts.ArenaNumber != 0:
+		layouter = &layout.ArenaLayouter{
+			Page:   block,
+			Number: opts.ArenaNumber,
 		}
-		if len(ranges) == 0 {
-			return res, fmt.Errorf("Range for '%s' cannot be empty", fAndSize)
+	case columnLayout == true:
+		layouter = &columns
+	case opts.FillFrom >= 0:
+		layouter = &layout.FillLayouter{
+			ColumnLayouter: columns,
+			From:           opts.FillFrom,
 		}
-		for i, r := range ranges {
-			if r.Begin >= len(tf.Codes) {
-				return res, fmt.Errorf("%d is out of range for %s in '%s'", r.Begin, fargs[0], fargs[2])
-			}
-			if r.End < 0 {
-				ranges[i].End = len(tf.Codes)
-			}
-			if ranges[i].End > len(tf.Codes) {
-				return res, fmt.Errorf("%d is out of range for %s in '%s'", ranges[i].End, fargs[0], fargs[2])
-			}
+	case opts.Strip == true:
+		layouter = &layout.StripLayouter{
+			ColumnLayouter: columns,
+			CutEvery:       opts.CutEvery,
 		}
+	}
+	if layouter == nil {
+		return nil, fmt.Errorf("Please specify a layout with either --arena-number, --column-number, --fill-from or --strip")
+	}
 
-		res = append(res, FamilyBlock{
-			Family: tf,
-			Size:   s,
-			Ranges: ranges,
-		})
+	if sized, ok := layouter.(layout.ContentSizedLayouter); ok == true {
+		page.Height, err = sized.ContentHeight(opts.DPI, blocks)
+		if err != nil {
+			return nil, err
+		}
+		layoutPage.Height = page.Height
+		rulerPage.Height = page.Height
 	}
-	return res, nil
-}
 
-func Execute() error {
-	opts := Options{}
-	if _, err := flags.Parse(&opts); err != nil {
-		return err
+	if len(opts.Impose) > 0 {
+		layouter = &layout.ImpositionLayouter{
+			Block:       layouter,
+			BlockWidth:  block.Width,
+			BlockHeight: block.Height,
+			Page:        layoutPage,
+			Gutter:      opts.Gutter,
+			Count:       opts.ImposeCount,
+		}
 	}
 
-	var drawer Drawer = nil
-	var err error
-	if filepath.Ext(opts.File) == ".svg" {
-		drawer, err = NewSVGDrawer(opts.File, opts.Width, opts.Height, opts.DPI)
-	} else {
-		drawer, err = NewImageDrawer(opts.File, opts.Width, opts.Height, opts.DPI)
+	if opts.Rulers == true {
+		layouter = &layout.RulerLayouter{Inner: layouter, Page: rulerPage}
+	}
+
+	if err := limits.check(opts.DPI, page, blocks); err != nil {
+		return nil, err
+	}
+
+	job := opts.Job
+	if len(job) == 0 {
+		job = strings.TrimSuffix(filepath.Base(opts.File), filepath.Ext(opts.File))
+	}
+	manifest := layout.NewManifest(job, time.Now(), opts.DPI, filepath.Base(opts.File), page, blocks)
+	manifestPath := opts.Manifest
+	if opts.SheetInfo != "none" {
+		layouter = &layout.SheetInfoLayouter{
+			Inner:    layouter,
+			Page:     page,
+			Manifest: manifest,
+			Footer:   opts.SheetInfo == "footer",
+		}
+		if len(manifestPath) == 0 {
+			manifestPath = strings.TrimSuffix(opts.File, filepath.Ext(opts.File)) + ".json"
+		}
 	}
+	engrave := drawing.IsEngraveFile(opts.File)
+	newVectorDrawer, vector := vectorDrawers[filepath.Ext(opts.File)]
+	if (engrave == true || vector == true) && (imageOpts.Mode != drawing.GrayMode || len(imageOpts.Separate) > 0) {
+		return nil, fmt.Errorf("--bilevel, --rgb and --separate only apply to PNG and TIFF outputs")
+	}
+	// the arena rotates the tags, which only vector outputs can draw
+	if opts.ArenaNumber != 0 && vector == false {
+		return nil, fmt.Errorf("--arena-number only applies to SVG, PDF, PS and EPS outputs")
+	}
+	newDrawer := func() (drawing.Drawer, error) {
+		if engrave == true {
+			return drawing.NewEngraveDrawer(opts.File, page.CanvasWidth(), page.CanvasHeight(), opts.DPI, drawing.EngraveOptions{
+				Spacing: opts.EngraveSpacing,
+				Speed:   opts.EngraveSpeed,
+				Power:   opts.EngravePower,
+				Lines:   opts.EngraveFill == "lines",
+			})
+		} else if vector == true {
+			return newVectorDrawer(opts.File, page.CanvasWidth(), page.CanvasHeight(), opts.DPI)
+		}
+		return drawing.NewImageDrawer(opts.File, page.CanvasWidth(), page.CanvasHeight(), opts.DPI, imageOpts)
+	}
+
+	return &sheet{
+		page:         page,
+		file:         opts.File,
+		layouter:     layouter,
+		blocks:       blocks,
+		manifest:     manifest,
+		manifestPath: manifestPath,
+		newDrawer:    newDrawer,
+	}, nil
+}
+
+// draw writes the sheet, and its manifest once the sheet is written.
+func (s *sheet) draw(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	drawer, err := s.newDrawer()
 	if err != nil {
 		return err
 	}
-	defer drawer.Close()
 
-	families, err := ExtractFamilyAndSizes(opts.FamilyAndSize)
+	err = s.page.Draw(drawer, s.layouter, s.blocks)
 	if err != nil {
+		drawer.Close()
+		return invalidJobError{fmt.Errorf("Cannot layout : %s", err)}
+	}
+	// image outputs check ctx between their bands, and remove
+	// themselves once it is done
+	if err := ctx.Err(); err != nil {
+		drawer.Close()
+		os.Remove(s.file)
+		return err
+	}
+	if err := drawer.Close(); err != nil {
 		return err
 	}
 
-	var layouter Layouter = nil
-
-	if opts.ArenaNumber != 0 && opts.ColumnNumber == 0 {
-		layouter = &ArenaLayouter{
-			Border: opts.PaperBorder,
-			Number: opts.ArenaNumber,
-			Width:  opts.Width,
-			Height: opts.Height,
-		}
-	} else if opts.ColumnNumber != 0 && opts.ArenaNumber == 0 {
-		layouter = &ColumnLayouter{
-			Width:            opts.Width,
-			Height:           opts.Height,
-			NColumns:         opts.ColumnNumber,
-			PaperBorder:      opts.PaperBorder,
-			FamilyMargin:     opts.FamilyMargin,
-			TagBorder:        opts.TagBorder,
-			LabelroundedSize: opts.LabelRoundedSize,
-			CutLine:          opts.CutLineRatio,
+	// the manifest only describes sheets which were produced
+	if len(s.manifestPath) > 0 {
+		if err := s.manifest.Write(s.manifestPath); err != nil {
+			return err
 		}
-	} else if opts.ColumnNumber != 0 && opts.ArenaNumber != 0 {
-		return fmt.Errorf("Please specify either a column or either an arena layout")
-	}
-	if layouter == nil {
-		return fmt.Errorf("Please specify a layout with either --arena-number or -- col-number")
+		log.Printf("Manifest %s written to %s", s.manifest.ID, s.manifestPath)
 	}
+	return nil
+}
 
-	err = layouter.Layout(drawer, families)
-	if err != nil {
-		log.Fatalf("Cannot layout : %s", err)
+// newParser returns the parser of opts, which takes the choices of
+// --packing from the layout package.
+func newParser(opts *Options, options flags.Options) *flags.Parser {
+	parser := flags.NewParser(opts, options)
+	parser.FindOptionByLongName("packing").Choices = layout.PackingStrategies
+	return parser
+}
+
+func Execute() error {
+	opts := Options{}
+	parser := newParser(&opts, flags.Default)
+	// without a command, the options describe the sheet to generate
+	parser.SubcommandsOptional = true
+	if _, err := parser.AddCommand("serve", "Serves an HTTP API and a web form generating sheets",
+		"Generates the sheets described by the posted jobs, and returns them with their manifest in a zip archive.",
+		&ServeCommand{}); err != nil {
+		return err
 	}
-	return nil
+	if _, err := parser.AddCommand("tag", "Renders tags as individual images",
+		"Renders each selected ID of a family in its own file, at a module size in pixels or mm.",
+		&TagCommand{}); err != nil {
+		return err
+	}
+	if _, err := parser.AddCommand("atlas", "Renders contact sheets of whole families",
+		"Renders the tags of families side by side with their ID, paginated, at a screen DPI.",
+		&AtlasCommand{}); err != nil {
+		return err
+	}
+	if _, err := parser.Parse(); err != nil {
+		return err
+	}
+	if parser.Active != nil {
+		return nil
+	}
+	return opts.Generate(context.Background(), Limits{})
 }
 
 func main() {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type GenerateSuite struct{}
+
+var _ = Suite(&GenerateSuite{})
+
+func (s *GenerateSuite) TestManifestOnlyDescribesProducedSheets(c *C) {
+	dir := c.MkDir()
+	opts, err := Job{Name: "sheet", Families: []string{"36h11:1.6:0-9"}, Format: "svg", DPI: 300}.options(dir)
+	c.Assert(err, IsNil)
+	c.Assert(opts.Generate(context.Background(), Limits{}), IsNil)
+	_, err = os.Stat(opts.Manifest)
+	c.Check(err, IsNil)
+
+	c.Assert(os.Remove(opts.Manifest), IsNil)
+	opts.FamilyAndSize = []string{"36h11:500:0-9"}
+	c.Check(opts.Generate(context.Background(), Limits{}), ErrorMatches, "Cannot layout : .*")
+	_, err = os.Stat(opts.Manifest)
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Check(len(files), Equals, 1)
+}
+
+func (s *GenerateSuite) TestArenaNeedsVectorOutputs(c *C) {
+	dir := c.MkDir()
+	opts, err := Job{Name: "sheet", Families: []string{"36h11:1.6:0-9"}, Format: "svg", DPI: 300}.options(dir)
+	c.Assert(err, IsNil)
+	opts.ColumnNumber = 0
+	opts.ArenaNumber = 4
+	c.Check(opts.Generate(context.Background(), Limits{}), IsNil)
+
+	for _, ext := range []string{".dxf", ".gcode", ".png"} {
+		opts.File = filepath.Join(dir, "arena"+ext)
+		c.Check(opts.Generate(context.Background(), Limits{}), ErrorMatches, "--arena-number only applies to SVG, PDF, PS and EPS outputs")
+		_, err = os.Stat(opts.File)
+		c.Check(os.IsNotExist(err), Equals, true)
+	}
+}
+
+func (s *GenerateSuite) TestTellsInvalidJobsFromHostFailures(c *C) {
+	dir := c.MkDir()
+	opts, err := Job{Name: "sheet", Families: []string{"36h11:1.6:0-9"}, Format: "svg", DPI: 300}.options(dir)
+	c.Assert(err, IsNil)
+
+	opts.File = filepath.Join(dir, "missing", "sheet.svg")
+	err = opts.Generate(context.Background(), Limits{})
+	c.Assert(err, NotNil)
+	_, invalid := err.(invalidJobError)
+	c.Check(invalid, Equals, false, Commentf("%s", err))
+
+	for _, families := range [][]string{{"36h11:1.6:0-9999"}, {"36h11:500:0-9"}} {
+		opts.File = filepath.Join(dir, "sheet.svg")
+		opts.FamilyAndSize = families
+		err = opts.Generate(context.Background(), Limits{MaxTags: 100})
+		c.Assert(err, NotNil)
+		_, invalid = err.(invalidJobError)
+		c.Check(invalid, Equals, true, Commentf("%s", err))
+	}
+}
+
+func (s *GenerateSuite) TestStopsWhenCancelled(c *C) {
+	dir := c.MkDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, format := range []string{"svg", "png"} {
+		opts, err := Job{Name: "sheet", Families: []string{"36h11:1.6:0-9"}, Format: format, DPI: 300}.options(dir)
+		c.Assert(err, IsNil)
+		c.Check(opts.Generate(ctx, Limits{}), Equals, context.Canceled)
+	}
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Check(len(files), Equals, 0)
+}
@@ -0,0 +1,136 @@
+package ranges
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+)
+
+// codeContext holds the family being filtered and the codes already
+// selected in the same block. The rotator is only computed by the
+// properties needing it, once per filtering.
+type codeContext struct {
+	tf       *families.TagFamily
+	selected []uint64
+	rotator  *families.CodeRotator
+}
+
+func (ctx *codeContext) codeRotator() (*families.CodeRotator, error) {
+	if ctx.rotator == nil {
+		r, err := ctx.tf.NewCodeRotator()
+		if err != nil {
+			return nil, err
+		}
+		ctx.rotator = r
+	}
+	return ctx.rotator, nil
+}
+
+// codeProperty computes a property of code.
+type codeProperty func(ctx *codeContext, code uint64) (int, error)
+
+var codeProperties = map[string]codeProperty{
+	"rotation": func(ctx *codeContext, code uint64) (int, error) {
+		r, err := ctx.codeRotator()
+		if err != nil {
+			return 0, err
+		}
+		return r.MinRotationDistance(code), nil
+	},
+	"balance": func(ctx *codeContext, code uint64) (int, error) {
+		return ctx.tf.BitBalance(code), nil
+	},
+	"distance": func(ctx *codeContext, code uint64) (int, error) {
+		res := ctx.tf.NBits
+		if len(ctx.selected) == 0 {
+			return res, nil
+		}
+		r, err := ctx.codeRotator()
+		if err != nil {
+			return 0, err
+		}
+		for _, s := range ctx.selected {
+			if d := r.Distance(code, s); d < res {
+				res = d
+			}
+		}
+		return res, nil
+	},
+}
+
+var codeComparisons = map[string]func(a, b int) bool{
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+	"=":  func(a, b int) bool { return a == b },
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+}
+
+type CodeFilter struct {
+	Property   string
+	Comparison string
+	Value      int
+}
+
+func (f CodeFilter) String() string {
+	return fmt.Sprintf("%s%s%d", f.Property, f.Comparison, f.Value)
+}
+
+var codeFilterRx = regexp.MustCompile(`^([a-z]+)\s*(<=|>=|==|!=|<|>|=)\s*([0-9]+)$`)
+
+func ExtractCodeFilter(s string) (CodeFilter, error) {
+	m := codeFilterRx.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return CodeFilter{}, fmt.Errorf("Invalid filter '%s': expected '<property><comparison><value>'", s)
+	}
+	if _, ok := codeProperties[m[1]]; ok == false {
+		return CodeFilter{}, fmt.Errorf("Unknown code property '%s' in '%s', available: balance, distance, rotation", m[1], s)
+	}
+	value, err := strconv.Atoi(m[3])
+	if err != nil {
+		return CodeFilter{}, err
+	}
+	return CodeFilter{Property: m[1], Comparison: m[2], Value: value}, nil
+}
+
+// FilterIDs keeps in order the ids whose code matches all
+// filters. The 'distance' property is computed against the codes
+// previously kept, so the selection is greedy. Copies of an ID are
+// kept or removed together.
+func FilterIDs(tf *families.TagFamily, ids []int, filters []CodeFilter) ([]int, error) {
+	res := []int{}
+	ctx := &codeContext{tf: tf}
+	kept := map[int]bool{}
+	for _, id := range ids {
+		if keep, ok := kept[id]; ok == true {
+			if keep == true {
+				res = append(res, id)
+			}
+			continue
+		}
+		code := tf.Codes[id]
+		keep := true
+		for _, f := range filters {
+			value, err := codeProperties[f.Property](ctx, code)
+			if err != nil {
+				return nil, err
+			}
+			if codeComparisons[f.Comparison](value, f.Value) == false {
+				keep = false
+				break
+			}
+		}
+		kept[id] = keep
+		if keep == false {
+			continue
+		}
+		res = append(res, id)
+		ctx.selected = append(ctx.selected, code)
+	}
+	return res, nil
+}
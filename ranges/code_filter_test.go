@@ -0,0 +1,105 @@
+package ranges
+
+import (
+	"github.com/formicidae-tracker/tag-layouter/families"
+	. "gopkg.in/check.v1"
+)
+
+type CodeFilterSuite struct {
+	Family *families.TagFamily
+}
+
+var _ = Suite(&CodeFilterSuite{})
+
+func (s *CodeFilterSuite) SetUpTest(c *C) {
+	// the tag16h5 layout, bits are arranged by quadrant
+	s.Family = &families.TagFamily{
+		Name:          "TEST16",
+		NBits:         16,
+		LocationX:     []int{1, 2, 3, 2, 4, 4, 4, 3, 4, 3, 2, 3, 1, 1, 1, 2},
+		LocationY:     []int{1, 1, 1, 2, 1, 2, 3, 2, 4, 4, 4, 3, 4, 3, 2, 3},
+		TotalWidth:    8,
+		WidthAtBorder: 6,
+		Codes:         []uint64{0xf000, 0xffff, 0x8421, 0xf0f0, 0x8000, 0x0f00},
+	}
+}
+
+func (s *CodeFilterSuite) TestCodeProperties(c *C) {
+	rotated, err := s.Family.Rotate90(0xf000)
+	c.Check(err, IsNil)
+	c.Check(rotated, Equals, uint64(0x0f00))
+
+	testdata := []struct {
+		Code             uint64
+		RotationDistance int
+		Balance          int
+	}{
+		{0xf000, 8, 8},
+		{0xffff, 0, 16},
+		{0x8421, 8, 8},
+		{0xf0f0, 0, 0},
+		{0x8000, 2, 14},
+	}
+	rotator, err := s.Family.NewCodeRotator()
+	c.Assert(err, IsNil)
+	for _, d := range testdata {
+		dist, err := s.Family.MinRotationDistance(d.Code)
+		c.Check(err, IsNil)
+		c.Check(dist, Equals, d.RotationDistance, Commentf("code 0x%04x", d.Code))
+		c.Check(rotator.MinRotationDistance(d.Code), Equals, d.RotationDistance, Commentf("code 0x%04x", d.Code))
+		c.Check(s.Family.BitBalance(d.Code), Equals, d.Balance, Commentf("code 0x%04x", d.Code))
+	}
+
+	dist, err := s.Family.Distance(0xf000, 0x0f00)
+	c.Check(err, IsNil)
+	c.Check(dist, Equals, 0)
+	// 0xf000 is a rotation of 0x0f00
+	c.Check(rotator.Distance(0x8000, 0x0f00), Equals, 3)
+
+	// a family whose bits do not map onto each other when rotated
+	skewed := *s.Family
+	skewed.LocationX = append([]int{0}, s.Family.LocationX[1:]...)
+	_, err = skewed.NewCodeRotator()
+	c.Check(err, ErrorMatches, "family TEST16 is not invariant by rotation: bit 0 has no rotated counterpart")
+}
+
+func (s *CodeFilterSuite) TestFilterIDs(c *C) {
+	testdata := []struct {
+		Input    string
+		Expected []int
+	}{
+		{"rotation>=2", []int{0, 2, 4, 5}},
+		{"balance<=8", []int{0, 2, 3, 5}},
+		{"rotation>=2;distance>2", []int{0, 2, 4}},
+		{"distance>=2", []int{0, 1, 2, 3, 4}},
+		{"0x2;1;4x3;5;distance>=2", []int{0, 0, 1, 4, 4, 4}},
+		{"rotation >= 2 balance<= 8", []int{0, 2, 5}},
+		{"1 rotation\t< 3, 4", []int{1, 4}},
+	}
+
+	for _, d := range testdata {
+		e, err := ParseRangeExpression(d.Input)
+		c.Assert(err, IsNil, Commentf("Parsing '%s'", d.Input))
+		ids, err := e.Select(s.Family)
+		c.Check(err, IsNil)
+		c.Check(ids, DeepEquals, d.Expected, Commentf("Filtering '%s'", d.Input))
+	}
+
+	_, err := ExtractCodeFilter("hamming>3")
+	c.Check(err, ErrorMatches, "Unknown code property 'hamming'.*")
+	_, err = ExtractCodeFilter("rotation~3")
+	c.Check(err, ErrorMatches, "Invalid filter.*")
+}
+
+func (s *CodeFilterSuite) TestRangesOfIDs(c *C) {
+	c.Check(RangesOfIDs([]int{0, 1, 2, 5, 7, 8, 8, 9, 9, 3}), DeepEquals,
+		[]Range{
+			Range{Begin: 0, End: 3},
+			Range{Begin: 5, End: 6},
+			Range{Begin: 7, End: 8},
+			Range{Begin: 8, End: 10, Copies: 2},
+			Range{Begin: 3, End: 4},
+		})
+	c.Check(IDsOfRanges([]Range{Range{Begin: 1, End: 3, Copies: 2}}), DeepEquals, []int{1, 1, 2, 2})
+	c.Check(IDsOfRanges([]Range{Range{Begin: 0, End: 3}, Range{Begin: 5, End: 6}}), DeepEquals, []int{0, 1, 2, 5})
+}
@@ -0,0 +1,128 @@
+package ranges
+
+import (
+	"fmt"
+)
+
+type Range struct {
+	Begin, End int
+	// Copies is the number of copies of each ID, 0 means a single one
+	Copies int
+}
+
+func (r Range) Len() int {
+	return r.End - r.Begin
+}
+
+func (r Range) NumberOfCopies() int {
+	if r.Copies < 1 {
+		return 1
+	}
+	return r.Copies
+}
+
+func (r Range) NumberOfTags() int {
+	return r.Len() * r.NumberOfCopies()
+}
+
+func (r Range) String() string {
+	res := ""
+	if r.Len() == 1 {
+		res = fmt.Sprintf("%d", r.Begin)
+	} else if r.End < 0 {
+		res = fmt.Sprintf("%d-", r.Begin)
+	} else {
+		res = fmt.Sprintf("%d-%d", r.Begin, r.End-1)
+	}
+	if r.NumberOfCopies() > 1 {
+		res += fmt.Sprintf("x%d", r.Copies)
+	}
+	return res
+}
+
+func (i rangeItem) toRange() (Range, error) {
+	if i.isPlainRange() == false {
+		return Range{}, &RangeError{
+			Input:   i.Input,
+			Source:  i.Source,
+			Column:  i.Column,
+			Message: "only ranges XX XX- -XX XX-YY and copies XXxN are supported",
+		}
+	}
+	copies := 0
+	if i.Count > 1 {
+		copies = i.Count
+	}
+	if i.End < 0 {
+		return Range{Begin: i.Begin, End: -1, Copies: copies}, nil
+	}
+	return Range{Begin: i.Begin, End: i.End + 1, Copies: copies}, nil
+}
+
+// ExtractRange parses a single inclusive range. An open range such
+// as '42-' has an End of -1.
+func ExtractRange(s string) (Range, error) {
+	p := &rangeParser{input: s}
+	item, err := p.parseItem()
+	if err != nil {
+		return Range{}, err
+	}
+	if p.eof() == false {
+		return Range{}, p.errorf(p.pos, "unexpected %s", p.describe())
+	}
+	return item.toRange()
+}
+
+func ExtractRanges(s string) ([]Range, error) {
+	p := &rangeParser{input: s}
+	items, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	var res []Range
+	for _, i := range items {
+		r, err := i.toRange()
+		if err != nil {
+			return res, err
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// IDsOfRanges lists the IDs of ranges, copies of the same ID are
+// next to each other.
+func IDsOfRanges(ranges []Range) []int {
+	res := []int{}
+	for _, r := range ranges {
+		for i := r.Begin; i < r.End; i++ {
+			for c := 0; c < r.NumberOfCopies(); c++ {
+				res = append(res, i)
+			}
+		}
+	}
+	return res
+}
+
+// RangesOfIDs is the inverse of IDsOfRanges: repeated IDs are
+// counted as copies and consecutive IDs are merged.
+func RangesOfIDs(ids []int) []Range {
+	var res []Range
+	for i := 0; i < len(ids); {
+		id := ids[i]
+		copies := 1
+		for i+copies < len(ids) && ids[i+copies] == id {
+			copies++
+		}
+		i += copies
+		if copies == 1 {
+			copies = 0
+		}
+		if len(res) > 0 && res[len(res)-1].End == id && res[len(res)-1].Copies == copies {
+			res[len(res)-1].End += 1
+			continue
+		}
+		res = append(res, Range{Begin: id, End: id + 1, Copies: copies})
+	}
+	return res
+}
@@ -0,0 +1,455 @@
+// Package ranges parses the range expressions selecting the tags of
+// a family.
+package ranges
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+)
+
+// RangeError reports an invalid range expression and the column of
+// the offending character. In an included file, Source is the file
+// and line, and the content of the line is never reported, as the
+// file may not be meant to be read by whoever sees the error.
+type RangeError struct {
+	// Input is the expression, empty in included files
+	Input   string
+	Source  string
+	Column  int
+	Message string
+}
+
+func (e *RangeError) Error() string {
+	if len(e.Source) > 0 {
+		return fmt.Sprintf("invalid range at %s, column %d: %s", e.Source, e.Column, e.Message)
+	}
+	return fmt.Sprintf("invalid range '%s' at column %d: %s", e.Input, e.Column, e.Message)
+}
+
+type rangeItem struct {
+	Input   string
+	Source  string
+	Column  int
+	Exclude bool
+	Begin   int
+	// End is inclusive, -1 means up to the last code of the family
+	End      int
+	Step     int
+	Count    int
+	Filter   *CodeFilter
+	Included []rangeItem
+}
+
+func (i rangeItem) isPlainRange() bool {
+	return i.Exclude == false && i.Step == 1 && i.Filter == nil && i.Included == nil
+}
+
+// RangeExpression is a parsed list of IDs selection. Items are
+// separated by ';', ',' or spaces and can be:
+//
+//	N, N-, -N, N-M    inclusive ranges, N- goes up to the last code
+//	N-M:S             every S IDs in N-M
+//	N-Mxk             k copies of each ID in N-M
+//	!N-M              excludes IDs from the selection
+//	property>=value   code filter, see ExtractCodeFilter, spaces may
+//	                  surround the comparison
+//	@file             IDs listed in file, '#' starts a comment
+type RangeExpression struct {
+	input string
+	items []rangeItem
+}
+
+const maxRangeFileDepth = 8
+
+type rangeParser struct {
+	input  string
+	source string
+	dir    string
+	depth  int
+	pos    int
+}
+
+func ParseRangeExpression(s string) (*RangeExpression, error) {
+	p := &rangeParser{input: s, dir: "."}
+	items, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &RangeExpression{input: s, items: items}, nil
+}
+
+// reported returns the input as reported in the errors.
+func (p *rangeParser) reported() string {
+	if len(p.source) > 0 {
+		return ""
+	}
+	return p.input
+}
+
+func (p *rangeParser) errorf(column int, format string, args ...interface{}) error {
+	return &RangeError{
+		Input:   p.reported(),
+		Source:  p.source,
+		Column:  column + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *rangeParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *rangeParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func isRangeSeparator(c byte) bool {
+	return c == ';' || c == ',' || unicode.IsSpace(rune(c))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *rangeParser) describe() string {
+	if p.eof() {
+		return "end of input"
+	}
+	if len(p.source) > 0 {
+		return "character"
+	}
+	return fmt.Sprintf("'%c'", p.peek())
+}
+
+func (p *rangeParser) parse() ([]rangeItem, error) {
+	var items []rangeItem
+	for {
+		for p.eof() == false && isRangeSeparator(p.peek()) {
+			p.pos++
+		}
+		if p.eof() {
+			return items, nil
+		}
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.eof() == false && isRangeSeparator(p.peek()) == false {
+			return nil, p.errorf(p.pos, "unexpected %s, expected ';' or ','", p.describe())
+		}
+	}
+}
+
+func (p *rangeParser) parseNumber() (int, error) {
+	start := p.pos
+	for p.eof() == false && isDigit(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, p.errorf(p.pos, "expected a number, got %s", p.describe())
+	}
+	res := 0
+	for _, c := range p.input[start:p.pos] {
+		res = 10*res + int(c-'0')
+		if res > 1<<30 {
+			return 0, p.errorf(start, "number is too large")
+		}
+	}
+	return res, nil
+}
+
+func (p *rangeParser) parseItem() (rangeItem, error) {
+	item := rangeItem{
+		Input:  p.reported(),
+		Source: p.source,
+		Column: p.pos + 1,
+		Step:   1,
+		Count:  1,
+	}
+	c := p.peek()
+	switch {
+	case c == '@':
+		return p.parseFile(item)
+	case unicode.IsLetter(rune(c)):
+		return p.parseFilter(item)
+	case c == '!':
+		item.Exclude = true
+		p.pos++
+	}
+
+	if err := p.parseBounds(&item); err != nil {
+		return item, err
+	}
+
+	if p.peek() == ':' {
+		p.pos++
+		step, err := p.parseNumber()
+		if err != nil {
+			return item, err
+		}
+		if step == 0 {
+			return item, p.errorf(p.pos-1, "step must be strictly positive")
+		}
+		item.Step = step
+	}
+
+	if p.peek() == 'x' {
+		if item.Exclude == true {
+			return item, p.errorf(p.pos, "exclusions cannot have copies")
+		}
+		p.pos++
+		count, err := p.parseNumber()
+		if err != nil {
+			return item, err
+		}
+		if count == 0 {
+			return item, p.errorf(p.pos-1, "number of copies must be strictly positive")
+		}
+		item.Count = count
+	}
+	return item, nil
+}
+
+func (p *rangeParser) parseBounds(item *rangeItem) error {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+		end, err := p.parseNumber()
+		if err != nil {
+			return err
+		}
+		item.Begin, item.End = 0, end
+		return nil
+	}
+
+	begin, err := p.parseNumber()
+	if err != nil {
+		return err
+	}
+	item.Begin, item.End = begin, begin
+	if p.peek() != '-' {
+		return nil
+	}
+	p.pos++
+	if isDigit(p.peek()) == false {
+		item.End = -1
+		return nil
+	}
+	end, err := p.parseNumber()
+	if err != nil {
+		return err
+	}
+	if end < begin && len(p.source) > 0 {
+		return p.errorf(start, "reversed range")
+	}
+	if end < begin {
+		return p.errorf(start, "reversed range %d-%d", begin, end)
+	}
+	item.End = end
+	return nil
+}
+
+func isComparison(c byte) bool {
+	return strings.IndexByte("<>=!", c) >= 0
+}
+
+// skipSpacesBefore skips the spaces before a character matching
+// accept, and returns false if there is none.
+func (p *rangeParser) skipSpacesBefore(accept func(c byte) bool) bool {
+	end := p.pos
+	for end < len(p.input) && unicode.IsSpace(rune(p.input[end])) {
+		end++
+	}
+	if end == len(p.input) || accept(p.input[end]) == false {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// parseFilter reads a filter, where spaces may surround the
+// comparison rather than separate items.
+func (p *rangeParser) parseFilter(item rangeItem) (rangeItem, error) {
+	start := p.pos
+	for p.eof() == false && unicode.IsLetter(rune(p.peek())) {
+		p.pos++
+	}
+	if p.skipSpacesBefore(isComparison) == true {
+		for p.eof() == false && isComparison(p.peek()) {
+			p.pos++
+		}
+		p.skipSpacesBefore(isDigit)
+	}
+	for p.eof() == false && isRangeSeparator(p.peek()) == false {
+		p.pos++
+	}
+	f, err := ExtractCodeFilter(p.input[start:p.pos])
+	if err != nil && len(p.source) > 0 {
+		return item, p.errorf(start, "invalid filter")
+	}
+	if err != nil {
+		return item, p.errorf(start, "%s", err)
+	}
+	item.Filter = &f
+	return item, nil
+}
+
+func (p *rangeParser) parseFile(item rangeItem) (rangeItem, error) {
+	p.pos++
+	start := p.pos
+	for p.eof() == false && isRangeSeparator(p.peek()) == false {
+		p.pos++
+	}
+	if start == p.pos {
+		return item, p.errorf(start, "expected a file name after '@'")
+	}
+	if p.depth >= maxRangeFileDepth {
+		return item, p.errorf(start, "too many nested files")
+	}
+	path := p.input[start:p.pos]
+	if filepath.IsAbs(path) == false {
+		path = filepath.Join(p.dir, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return item, p.errorf(start, "%s", err)
+	}
+
+	item.Included = []rangeItem{}
+	for i, line := range strings.Split(string(data), "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		sub := &rangeParser{
+			input:  line,
+			source: fmt.Sprintf("%s:%d", path, i+1),
+			dir:    filepath.Dir(path),
+			depth:  p.depth + 1,
+		}
+		items, err := sub.parse()
+		if err != nil {
+			return item, err
+		}
+		item.Included = append(item.Included, items...)
+	}
+	return item, nil
+}
+
+func (e *RangeExpression) String() string {
+	return e.input
+}
+
+func (e *RangeExpression) IsEmpty() bool {
+	return len(e.items) == 0
+}
+
+func (e *RangeExpression) HasFilters() bool {
+	return hasFilters(e.items)
+}
+
+func hasFilters(items []rangeItem) bool {
+	for _, i := range items {
+		if i.Filter != nil || hasFilters(i.Included) {
+			return true
+		}
+	}
+	return false
+}
+
+type rangeSelection struct {
+	tf       *families.TagFamily
+	ids      []int
+	included bool
+	excluded map[int]bool
+	filters  []CodeFilter
+}
+
+func (s *rangeSelection) evaluate(items []rangeItem) error {
+	n := len(s.tf.Codes)
+	for _, i := range items {
+		if i.Filter != nil {
+			s.filters = append(s.filters, *i.Filter)
+			continue
+		}
+		if i.Included != nil {
+			if err := s.evaluate(i.Included); err != nil {
+				return err
+			}
+			continue
+		}
+		end := i.End
+		if end < 0 {
+			end = n - 1
+		}
+		if i.Begin >= n || end >= n {
+			id := fmt.Sprintf("%d", max(i.Begin, end))
+			if len(i.Source) > 0 {
+				id = "ID"
+			}
+			return &RangeError{
+				Input:   i.Input,
+				Source:  i.Source,
+				Column:  i.Column,
+				Message: fmt.Sprintf("%s is out of range for %s (%d codes)", id, s.tf.Name, n),
+			}
+		}
+		if i.Exclude == true {
+			for id := i.Begin; id <= end; id += i.Step {
+				s.excluded[id] = true
+			}
+			continue
+		}
+		s.included = true
+		for id := i.Begin; id <= end; id += i.Step {
+			for c := 0; c < i.Count; c++ {
+				s.ids = append(s.ids, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Select returns in order the IDs of tf selected by the expression.
+// An expression with only exclusions or filters applies to the whole
+// family.
+func (e *RangeExpression) Select(tf *families.TagFamily) ([]int, error) {
+	s := &rangeSelection{
+		tf:       tf,
+		excluded: map[int]bool{},
+	}
+	if err := s.evaluate(e.items); err != nil {
+		return nil, err
+	}
+	if s.included == false {
+		for id := range tf.Codes {
+			s.ids = append(s.ids, id)
+		}
+	}
+
+	res := make([]int, 0, len(s.ids))
+	for _, id := range s.ids {
+		if s.excluded[id] == false {
+			res = append(res, id)
+		}
+	}
+	if len(s.filters) == 0 {
+		return res, nil
+	}
+	return FilterIDs(tf, res, s.filters)
+}
+
+func max(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,183 @@
+package ranges
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/formicidae-tracker/tag-layouter/families"
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type RangeSuite struct{}
+
+var _ = Suite(&RangeSuite{})
+
+func (s *RangeSuite) TestExtractRange(c *C) {
+	testdata := []struct {
+		Input    string
+		Expected Range
+	}{
+		{
+			"42",
+			Range{Begin: 42, End: 43},
+		},
+		{
+			"-42",
+			Range{Begin: 0, End: 43},
+		},
+		{
+			"40-42",
+			Range{Begin: 40, End: 43},
+		},
+		{
+			"42-",
+			Range{Begin: 42, End: -1},
+		},
+	}
+
+	for _, d := range testdata {
+		r, err := ExtractRange(d.Input)
+		c.Check(err, IsNil, Commentf("Parsing '%s'", d.Input))
+		c.Check(r, Equals, d.Expected)
+	}
+
+}
+
+func (s *RangeSuite) TestExtractRanges(c *C) {
+	testdata := []struct {
+		Input    string
+		Expected []Range
+	}{
+		{
+			"",
+			nil,
+		},
+		{
+			"0;1;2;3",
+			[]Range{Range{Begin: 0, End: 1}, Range{Begin: 1, End: 2}, Range{Begin: 2, End: 3}, Range{Begin: 3, End: 4}},
+		},
+		{
+			"-41;42-",
+			[]Range{Range{Begin: 0, End: 42}, Range{Begin: 42, End: -1}},
+		},
+		{
+			"0x4;1-2x2",
+			[]Range{Range{Begin: 0, End: 1, Copies: 4}, Range{Begin: 1, End: 3, Copies: 2}},
+		},
+		{
+			"1,3-4",
+			[]Range{Range{Begin: 1, End: 2}, Range{Begin: 3, End: 5}},
+		},
+	}
+
+	for _, d := range testdata {
+		r, err := ExtractRanges(d.Input)
+		c.Check(err, IsNil, Commentf("Parsing '%s'", d.Input))
+		c.Check(r, DeepEquals, d.Expected)
+	}
+
+}
+
+func (s *RangeSuite) TestRangeString(c *C) {
+	c.Check(Range{Begin: 42, End: 43}.String(), Equals, "42")
+	c.Check(Range{Begin: 0, End: 43}.String(), Equals, "0-42")
+	c.Check(Range{Begin: 42, End: -1}.String(), Equals, "42-")
+	c.Check(Range{Begin: 0, End: 1, Copies: 4}.String(), Equals, "0x4")
+	c.Check(Range{Begin: 0, End: 10, Copies: 2}.String(), Equals, "0-9x2")
+}
+
+func (s *RangeSuite) TestRangeExpression(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "ids.txt"), []byte("# queens\n3\n5 7\n"), 0644), IsNil)
+
+	tf := &families.TagFamily{Name: "TEST", Codes: make([]uint64, 20)}
+
+	testdata := []struct {
+		Input    string
+		Expected []int
+	}{
+		{"2-4", []int{2, 3, 4}},
+		{"0-10:3", []int{0, 3, 6, 9}},
+		{"16-", []int{16, 17, 18, 19}},
+		{"0-5,!2-3", []int{0, 1, 4, 5}},
+		{"!0-15", []int{16, 17, 18, 19}},
+		{"0x4;1", []int{0, 0, 0, 0, 1}},
+		{"0-1x2", []int{0, 0, 1, 1}},
+		{"@" + filepath.Join(dir, "ids.txt") + ";!5", []int{3, 7}},
+	}
+
+	for _, d := range testdata {
+		e, err := ParseRangeExpression(d.Input)
+		if c.Check(err, IsNil, Commentf("Parsing '%s'", d.Input)) == false {
+			continue
+		}
+		ids, err := e.Select(tf)
+		c.Check(err, IsNil, Commentf("Selecting '%s'", d.Input))
+		c.Check(ids, DeepEquals, d.Expected, Commentf("Selecting '%s'", d.Input))
+	}
+}
+
+func (s *RangeSuite) TestRangeExpressionErrors(c *C) {
+	testdata := []struct {
+		Input  string
+		Column int
+	}{
+		{"0;5-2", 3},
+		{"0-4:0", 5},
+		{"0-4x", 5},
+		{"12a", 3},
+		{"0;!4x2", 5},
+		{"1;rotation~3", 3},
+		{"1;@", 4},
+	}
+
+	for _, d := range testdata {
+		_, err := ParseRangeExpression(d.Input)
+		rerr, ok := err.(*RangeError)
+		if c.Check(ok, Equals, true, Commentf("Parsing '%s': %s", d.Input, err)) == false {
+			continue
+		}
+		c.Check(rerr.Column, Equals, d.Column, Commentf("Parsing '%s': %s", d.Input, err))
+	}
+
+	e, err := ParseRangeExpression("0-19;18-20")
+	c.Assert(err, IsNil)
+	_, err = e.Select(&families.TagFamily{Name: "TEST", Codes: make([]uint64, 20)})
+	c.Check(err, ErrorMatches, "invalid range '0-19;18-20' at column 6: 20 is out of range for TEST \\(20 codes\\)")
+}
+
+func (s *RangeSuite) TestIncludedFileErrorsHideTheContent(c *C) {
+	dir := c.MkDir()
+	testdata := []struct {
+		Content string
+		Message string
+	}{
+		{"1\nroot:x:0:0:root:/root:/bin/bash\n", "invalid range at .*secret.txt:2, column 1: invalid filter"},
+		{"3\n12a\n", "invalid range at .*secret.txt:2, column 3: unexpected character, expected ';' or ','"},
+		{"2 secret>=3\n", "invalid range at .*secret.txt:1, column 3: invalid filter"},
+		{"29-17\n", "invalid range at .*secret.txt:1, column 1: reversed range"},
+		{"27\n", "invalid range at .*secret.txt:1, column 1: ID is out of range for TEST \\(20 codes\\)"},
+	}
+	tf := &families.TagFamily{Name: "TEST", Codes: make([]uint64, 20)}
+	for _, d := range testdata {
+		path := filepath.Join(dir, "secret.txt")
+		c.Assert(ioutil.WriteFile(path, []byte(d.Content), 0644), IsNil)
+		e, err := ParseRangeExpression("@" + path)
+		if err == nil {
+			_, err = e.Select(tf)
+		}
+		c.Assert(err, NotNil, Commentf("content: %s", d.Content))
+		c.Check(err, ErrorMatches, d.Message)
+		c.Check(err.(*RangeError).Input, Equals, "")
+		// the path may contain anything, only the rest is checked
+		reported := strings.TrimPrefix(err.Error(), "invalid range at "+path)
+		for _, secret := range []string{"root", "secret", "12", "29", "17", "27"} {
+			c.Check(strings.Contains(reported, secret), Equals, false, Commentf("%s", err))
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/formicidae-tracker/tag-layouter/layout"
+	"github.com/jessevdk/go-flags"
+)
+
+// Limits protect the host from the jobs it generates. Zero values are
+// unlimited.
+type Limits struct {
+	MaxDPI    int
+	MaxWidth  float64
+	MaxHeight float64
+	MaxTags   int
+}
+
+func (l Limits) check(DPI int, page layout.Page, blocks []layout.FamilyBlock) error {
+	if l.MaxDPI > 0 && DPI > l.MaxDPI {
+		return fmt.Errorf("DPI %d exceeds the limit of %d", DPI, l.MaxDPI)
+	}
+	if l.MaxWidth > 0 && page.CanvasWidth() > l.MaxWidth {
+		return fmt.Errorf("Page width %.1fmm exceeds the limit of %.1fmm", page.CanvasWidth(), l.MaxWidth)
+	}
+	if l.MaxHeight > 0 && page.CanvasHeight() > l.MaxHeight {
+		return fmt.Errorf("Page height %.1fmm exceeds the limit of %.1fmm", page.CanvasHeight(), l.MaxHeight)
+	}
+	if l.MaxTags > 0 {
+		n := 0
+		for _, b := range blocks {
+			n += b.NumberOfTags()
+		}
+		if n > l.MaxTags {
+			return fmt.Errorf("%d tags exceed the limit of %d", n, l.MaxTags)
+		}
+	}
+	return nil
+}
+
+// Job describes a sheet requested to the HTTP service. Zero values
+// keep the defaults of the command line, and a single column is laid
+// out unless another layout is requested.
+type Job struct {
+	Name        string   `json:"name"`
+	Format      string   `json:"format"`
+	Families    []string `json:"families"`
+	Paper       string   `json:"paper"`
+	Landscape   bool     `json:"landscape"`
+	Width       float64  `json:"width"`
+	Height      float64  `json:"height"`
+	DPI         int      `json:"dpi"`
+	Columns     int      `json:"columns"`
+	AutoColumns bool     `json:"auto_columns"`
+	Packing     string   `json:"packing"`
+	Strip       bool     `json:"strip"`
+	CutEvery    int      `json:"cut_every"`
+	Bilevel     bool     `json:"bilevel"`
+	RGB         bool     `json:"rgb"`
+	Separate    string   `json:"separate"`
+	SheetInfo   string   `json:"sheet_info"`
+	Rulers      bool     `json:"rulers"`
+}
+
+// jobFormats are the output formats of the HTTP service.
+var jobFormats = map[string]string{
+	"png":  ".png",
+	"tiff": ".tiff",
+	"svg":  ".svg",
+	"pdf":  ".pdf",
+}
+
+var unsafeFileCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// fileName returns the name of the files of the job.
+func (j Job) fileName() string {
+	res := strings.Trim(unsafeFileCharacters.ReplaceAllString(j.Name, "_"), "._")
+	if len(res) == 0 {
+		return "sheet"
+	}
+	return res
+}
+
+// options returns the command line options generating the job in dir.
+func (j Job) options(dir string) (*Options, error) {
+	opts := &Options{}
+	if _, err := newParser(opts, flags.None).ParseArgs([]string{}); err != nil {
+		return nil, err
+	}
+	if len(j.Families) == 0 {
+		return nil, fmt.Errorf("At least one family is required")
+	}
+	for _, f := range j.Families {
+		// ranges may include files, which are read on the host
+		if strings.Contains(f, "@") == true {
+			return nil, fmt.Errorf("Family '%s' includes a file, which jobs cannot do", f)
+		}
+	}
+	format := j.Format
+	if len(format) == 0 {
+		format = "png"
+	}
+	ext, ok := jobFormats[format]
+	if ok == false {
+		return nil, fmt.Errorf("Unsupported format '%s', expected png, tiff, svg or pdf", format)
+	}
+	if len(j.Packing) > 0 {
+		if _, err := layout.NewPacker(j.Packing, 0); err != nil {
+			return nil, err
+		}
+		opts.Packing = j.Packing
+	}
+	switch j.SheetInfo {
+	case "":
+	case "none", "header", "footer":
+		opts.SheetInfo = j.SheetInfo
+	default:
+		return nil, fmt.Errorf("Unsupported sheet info '%s', expected none, header or footer", j.SheetInfo)
+	}
+
+	name := j.fileName()
+	opts.File = filepath.Join(dir, name+ext)
+	opts.Manifest = filepath.Join(dir, name+".json")
+	opts.Job = j.Name
+	if len(opts.Job) == 0 {
+		opts.Job = name
+	}
+	opts.FamilyAndSize = j.Families
+	opts.Paper = j.Paper
+	opts.Landscape = j.Landscape
+	if j.Width > 0 {
+		opts.Width = j.Width
+	}
+	if j.Height > 0 {
+		opts.Height = j.Height
+	}
+	if j.DPI > 0 {
+		opts.DPI = j.DPI
+	}
+	opts.ColumnNumber = j.Columns
+	opts.AutoColumns = j.AutoColumns
+	opts.Strip = j.Strip
+	opts.CutEvery = j.CutEvery
+	if opts.ColumnNumber == 0 && opts.AutoColumns == false && opts.Strip == false {
+		opts.ColumnNumber = 1
+	}
+	opts.Bilevel = j.Bilevel
+	opts.RGB = j.RGB
+	opts.Separate = j.Separate
+	opts.Rulers = j.Rulers
+	return opts, nil
+}
+
+// jobFromForm reads a job from the fields of the web form, where the
+// families are one per line.
+func jobFromForm(form url.Values) (Job, error) {
+	res := Job{
+		Name:      form.Get("name"),
+		Format:    form.Get("format"),
+		Paper:     form.Get("paper"),
+		Landscape: form.Get("landscape") == "on",
+		Packing:   form.Get("packing"),
+		Bilevel:   form.Get("bilevel") == "on",
+		SheetInfo: form.Get("sheet_info"),
+		Rulers:    form.Get("rulers") == "on",
+	}
+	for _, line := range strings.Split(form.Get("families"), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			res.Families = append(res.Families, line)
+		}
+	}
+	for name, value := range map[string]*int{"dpi": &res.DPI, "columns": &res.Columns} {
+		s := strings.TrimSpace(form.Get(name))
+		if len(s) == 0 {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return res, fmt.Errorf("Invalid %s '%s'", name, s)
+		}
+		*value = v
+	}
+	return res, nil
+}
+
+// maxRequestSize is the maximal size of a job description in bytes.
+const maxRequestSize = 1 << 20
+
+type ServeCommand struct {
+	Address    string        `long:"address" description:"Address to listen on" default:":8080"`
+	MaxDPI     int           `long:"max-dpi" description:"Maximal DPI of a job" default:"2400"`
+	MaxWidth   float64       `long:"max-width" description:"Maximal page width of a job in mm, bleed included" default:"420"`
+	MaxHeight  float64       `long:"max-height" description:"Maximal page height of a job in mm, bleed and strips included" default:"1000"`
+	MaxTags    int           `long:"max-tags" description:"Maximal number of tags of a job" default:"10000"`
+	MaxJobs    int           `long:"max-jobs" description:"Number of jobs generated concurrently, further requests are refused" default:"1"`
+	JobTimeout time.Duration `long:"job-timeout" description:"Maximal duration to generate a job, longer jobs are stopped, 0 for no limit" default:"10m"`
+}
+
+// sendTimeout is the time left to send the archive of a job once it
+// is generated.
+const sendTimeout = 5 * time.Minute
+
+// server generates the jobs it receives in temporary directories.
+type server struct {
+	limits Limits
+	// slots holds a token per job being generated
+	slots chan struct{}
+	// timeout bounds the generation of a job, 0 means no bound
+	timeout time.Duration
+}
+
+func newServer(limits Limits, maxJobs int, timeout time.Duration) *server {
+	if maxJobs < 1 {
+		maxJobs = 1
+	}
+	return &server{limits: limits, slots: make(chan struct{}, maxJobs), timeout: timeout}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveForm)
+	mux.HandleFunc("/sheets", s.serveSheet)
+	return mux
+}
+
+var formTemplate = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>tag-layouter</title></head>
+<body>
+<h1>tag-layouter</h1>
+<form method="post" action="sheets">
+<p><label>Job name <input name="name" value="sheet"></label></p>
+<p><label>Families, one '&lt;name&gt;:&lt;size&gt;:&lt;range&gt;' per line<br>
+<textarea name="families" rows="4" cols="40">36h11:1.6:0-99</textarea></label></p>
+<p><label>Format <select name="format">
+<option>png</option><option>tiff</option><option>svg</option><option>pdf</option>
+</select></label>
+<label><input type="checkbox" name="bilevel"> bilevel</label></p>
+<p><label>Paper <input name="paper" value="a4"></label>
+<label><input type="checkbox" name="landscape"> landscape</label></p>
+<p><label>DPI, at most {{.MaxDPI}} <input name="dpi" value="{{.MaxDPI}}"></label></p>
+<p><label>Columns <input name="columns" value="1"></label>
+<label>Packing <select name="packing">
+<option>column</option><option>shelf</option><option>guillotine</option><option>maxrects</option>
+</select></label></p>
+<p><label>Sheet info <select name="sheet_info">
+<option>none</option><option>header</option><option>footer</option>
+</select></label>
+<label><input type="checkbox" name="rulers"> rulers</label></p>
+<p>Pages are at most {{.MaxWidth}}x{{.MaxHeight}}mm with at most {{.MaxTags}} tags.</p>
+<p><input type="submit" value="Generate"></p>
+</form>
+</body>
+</html>
+`))
+
+func (s *server) serveForm(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := formTemplate.Execute(w, s.limits); err != nil {
+		log.Printf("Cannot write the form: %s", err)
+	}
+}
+
+// readJob reads a JSON job, or the fields of the web form.
+func readJob(r *http.Request) (Job, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") == true {
+		res := Job{}
+		if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+			return res, fmt.Errorf("Invalid job: %s", err)
+		}
+		return res, nil
+	}
+	if err := r.ParseForm(); err != nil {
+		return Job{}, err
+	}
+	return jobFromForm(r.PostForm)
+}
+
+func (s *server) serveSheet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Jobs must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	job, err := readJob(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "Too many jobs in progress", http.StatusServiceUnavailable)
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "tag-layouter")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	opts, err := job.options(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// the generation stops once the client is gone or the job is too
+	// long, the deferred calls then free its slot and its files
+	ctx := r.Context()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	if err := opts.Generate(ctx, s.limits); err != nil {
+		if ctx.Err() != nil {
+			log.Printf("Job '%s' stopped: %s", job.Name, err)
+			http.Error(w, fmt.Sprintf("Job exceeds the time limit of %s", s.timeout), http.StatusServiceUnavailable)
+			return
+		}
+		// only the job is to blame for invalid options
+		status := http.StatusInternalServerError
+		if _, ok := err.(invalidJobError); ok == true {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", job.fileName()))
+	if err := writeZip(w, dir); err != nil {
+		log.Printf("Cannot send job '%s': %s", job.Name, err)
+	}
+}
+
+// writeZip archives the files of dir, which holds the sheet, its
+// separations and its manifest.
+func writeZip(w io.Writer, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, fi := range files {
+		header, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		header.Method = zip.Deflate
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (c *ServeCommand) Execute(args []string) error {
+	s := newServer(Limits{
+		MaxDPI:    c.MaxDPI,
+		MaxWidth:  c.MaxWidth,
+		MaxHeight: c.MaxHeight,
+		MaxTags:   c.MaxTags,
+	}, c.MaxJobs, c.JobTimeout)
+	var writeTimeout time.Duration
+	if c.JobTimeout > 0 {
+		writeTimeout = c.JobTimeout + sendTimeout
+	}
+	server := &http.Server{
+		Addr:         c.Address,
+		Handler:      s.handler(),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: writeTimeout,
+	}
+	log.Printf("Serving sheets on %s", c.Address)
+	return server.ListenAndServe()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type ServeSuite struct {
+	server *server
+}
+
+var _ = Suite(&ServeSuite{})
+
+func (s *ServeSuite) SetUpTest(c *C) {
+	s.server = newServer(Limits{MaxDPI: 600, MaxWidth: 300, MaxHeight: 300, MaxTags: 100}, 1, time.Minute)
+}
+
+func (s *ServeSuite) post(contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/sheets", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	res := httptest.NewRecorder()
+	s.server.handler().ServeHTTP(res, req)
+	return res
+}
+
+func (s *ServeSuite) TestJobOptions(c *C) {
+	opts, err := Job{Name: "my job/1", Families: []string{"36h11:1.6"}, Format: "svg"}.options("out")
+	c.Assert(err, IsNil)
+	c.Check(opts.File, Equals, filepath.Join("out", "my_job_1.svg"))
+	c.Check(opts.Manifest, Equals, filepath.Join("out", "my_job_1.json"))
+	c.Check(opts.Job, Equals, "my job/1")
+	c.Check(opts.ColumnNumber, Equals, 1)
+	c.Check(opts.DPI, Equals, 2400)
+	c.Check(opts.Width, Equals, 210.0)
+	c.Check(opts.SheetInfo, Equals, "none")
+
+	opts, err = Job{Families: []string{"36h11:1.6"}, Strip: true, Width: 50}.options("out")
+	c.Assert(err, IsNil)
+	c.Check(opts.File, Equals, filepath.Join("out", "sheet.png"))
+	c.Check(opts.ColumnNumber, Equals, 0)
+	c.Check(opts.Width, Equals, 50.0)
+
+	_, err = Job{Families: []string{"36h11:1.6"}, Format: "dxf"}.options("out")
+	c.Check(err, ErrorMatches, "Unsupported format 'dxf', expected png, tiff, svg or pdf")
+	_, err = Job{}.options("out")
+	c.Check(err, ErrorMatches, "At least one family is required")
+	_, err = Job{Families: []string{"36h11:1.6"}, Packing: "spiral"}.options("out")
+	c.Check(err, ErrorMatches, "Unknown packing strategy 'spiral'")
+}
+
+func (s *ServeSuite) TestJobFromForm(c *C) {
+	job, err := jobFromForm(url.Values{
+		"name":      {"sheet"},
+		"families":  {"36h11:1.6:0-9\r\n\r\n36h10:2.0\r\n"},
+		"dpi":       {"1200"},
+		"columns":   {""},
+		"landscape": {"on"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(job.Families, DeepEquals, []string{"36h11:1.6:0-9", "36h10:2.0"})
+	c.Check(job.DPI, Equals, 1200)
+	c.Check(job.Columns, Equals, 0)
+	c.Check(job.Landscape, Equals, true)
+	c.Check(job.Bilevel, Equals, false)
+
+	_, err = jobFromForm(url.Values{"dpi": {"many"}})
+	c.Check(err, ErrorMatches, "Invalid dpi 'many'")
+}
+
+func (s *ServeSuite) TestServeSheet(c *C) {
+	res := s.post("application/json", `{"name":"test","families":["36h11:2.0:0-3"],"dpi":300,"paper":"a5"}`)
+	c.Assert(res.Code, Equals, http.StatusOK, Commentf("%s", res.Body.String()))
+	c.Check(res.Header().Get("Content-Type"), Equals, "application/zip")
+	c.Check(res.Header().Get("Content-Disposition"), Equals, `attachment; filename="test.zip"`)
+
+	data := res.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, IsNil)
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	c.Check(names, DeepEquals, []string{"test.json", "test.png"})
+
+	res = s.post("application/x-www-form-urlencoded", "families=36h11%3A2.0%3A0-3&dpi=300&format=svg&paper=a5")
+	c.Check(res.Code, Equals, http.StatusOK, Commentf("%s", res.Body.String()))
+}
+
+func (s *ServeSuite) TestServeLimits(c *C) {
+	testdata := []struct {
+		Job     string
+		Message string
+	}{
+		{`{"families":["36h11:2.0:0-3"],"dpi":1200}`, "DPI 1200 exceeds the limit of 600"},
+		{`{"families":["36h11:2.0:0-3"],"dpi":300,"paper":"a3"}`, "Page height 420.0mm exceeds the limit of 300.0mm"},
+		{`{"families":["36h11:2.0:0-200"],"dpi":300,"paper":"a5"}`, "201 tags exceed the limit of 100"},
+		{`{"families":["36h11:2.0:0-3"],"dpi":300,"format":"eps"}`, "Unsupported format 'eps', expected png, tiff, svg or pdf"},
+		{`{"families":"36h11"}`, "Invalid job: .*"},
+		{`{"families":["36h11:1.6:@/etc/passwd"],"dpi":300}`, "Family '36h11:1.6:@/etc/passwd' includes a file, which jobs cannot do"},
+		{`{"families":["36h11:1.6:0-3;@/etc/passwd"],"dpi":300}`, "Family .* includes a file, which jobs cannot do"},
+	}
+	for _, d := range testdata {
+		res := s.post("application/json", d.Job)
+		c.Check(res.Code, Equals, http.StatusBadRequest, Commentf("job: %s", d.Job))
+		c.Check(strings.TrimSpace(res.Body.String()), Matches, d.Message, Commentf("job: %s", d.Job))
+		c.Check(strings.Contains(res.Body.String(), "root:"), Equals, false, Commentf("job: %s", d.Job))
+	}
+}
+
+func (s *ServeSuite) TestServeRefusals(c *C) {
+	res := httptest.NewRecorder()
+	s.server.handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/sheets", nil))
+	c.Check(res.Code, Equals, http.StatusMethodNotAllowed)
+
+	res = httptest.NewRecorder()
+	s.server.handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.Check(res.Code, Equals, http.StatusOK)
+	body, _ := ioutil.ReadAll(res.Body)
+	c.Check(strings.Contains(string(body), "at most 600"), Equals, true)
+
+	// all the slots are taken
+	s.server.slots <- struct{}{}
+	res = s.post("application/json", `{"families":["36h11:2.0:0-3"],"dpi":300}`)
+	c.Check(res.Code, Equals, http.StatusServiceUnavailable)
+	c.Check(res.Header().Get("Retry-After"), Equals, "10")
+}
+
+func (s *ServeSuite) TestStopsLongJobs(c *C) {
+	tmp := c.MkDir()
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	c.Assert(os.Setenv("TMPDIR", tmp), IsNil)
+
+	s.server.timeout = time.Nanosecond
+	res := s.post("application/json", `{"families":["36h11:2.0:0-3"],"dpi":300,"format":"png"}`)
+	c.Check(res.Code, Equals, http.StatusServiceUnavailable)
+	c.Check(strings.TrimSpace(res.Body.String()), Equals, "Job exceeds the time limit of 1ns")
+	c.Check(len(s.server.slots), Equals, 0)
+	files, err := ioutil.ReadDir(tmp)
+	c.Assert(err, IsNil)
+	c.Check(len(files), Equals, 0)
+}
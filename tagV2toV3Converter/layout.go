@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Layout gives the location of each bit of a code, the first bit
+// being the most significant one. Locations are in modules from the
+// outer corner of the black border.
+type Layout struct {
+	X, Y []int
+}
+
+func (l Layout) NBits() int {
+	return len(l.X)
+}
+
+// OldLayout is the layout of apriltag 2 families: the d x d data bits
+// in row major order, within a black border of border modules.
+func OldLayout(d, border int) Layout {
+	res := Layout{}
+	for y := 0; y < d; y++ {
+		for x := 0; x < d; x++ {
+			res.X = append(res.X, border+x)
+			res.Y = append(res.Y, border+y)
+		}
+	}
+	return res
+}
+
+// NewLayout is the layout of the old-style families of apriltag 3.
+// The bits form four identical quadrants, each rotated by 90 degrees
+// clockwise from the previous one, so rotating a tag rotates its code
+// by a quarter of its bits. A quadrant lists the rings from the
+// outside in, along their top edge but the last module. An odd d has
+// a last bit in the center.
+func NewLayout(d, border int) Layout {
+	qx, qy := []int{}, []int{}
+	for r := 0; r < d/2; r++ {
+		for x := r; x < d-1-r; x++ {
+			qx = append(qx, x)
+			qy = append(qy, r)
+		}
+	}
+	res := Layout{}
+	for q := 0; q < 4; q++ {
+		for i := range qx {
+			x, y := qx[i], qy[i]
+			for k := 0; k < q; k++ {
+				x, y = d-1-y, x
+			}
+			res.X = append(res.X, border+x)
+			res.Y = append(res.Y, border+y)
+		}
+	}
+	if d%2 == 1 {
+		res.X = append(res.X, border+d/2)
+		res.Y = append(res.Y, border+d/2)
+	}
+	return res
+}
+
+// BuildCorrespondances returns for each bit of from the index of the
+// bit at the same location in to.
+func BuildCorrespondances(from, to Layout) ([]int, error) {
+	if from.NBits() != to.NBits() {
+		return nil, fmt.Errorf("Layouts have %d and %d bits", from.NBits(), to.NBits())
+	}
+	correspondances := []int{}
+
+	mapped := map[int]int{}
+
+	// build correspondances
+	for i, xFrom := range from.X {
+		yFrom := from.Y[i]
+		found := false
+		for j, xTo := range to.X {
+			if xTo != xFrom || to.Y[j] != yFrom {
+				continue
+			}
+			if mappedBit, ok := mapped[j]; ok == true {
+				return nil, fmt.Errorf("Destination bit %d is already mapped to %d, and want to map it to %d",
+					j, mappedBit, i)
+			}
+			mapped[j] = i
+			found = true
+			correspondances = append(correspondances, j)
+			break
+		}
+
+		if found == false {
+			return nil, fmt.Errorf("Could not find correspondances for bit %d", i)
+		}
+
+	}
+
+	return correspondances, nil
+}
+
+func ConvertCode(corr []int, old uint64) uint64 {
+	nbits := uint64(len(corr))
+	res := uint64(0)
+	for i := uint64(0); i < nbits; i++ {
+		if old&(uint64(1)<<(nbits-1-i)) == 0x00 {
+			continue
+		}
+		j := uint64(corr[i])
+		res |= uint64(1) << (nbits - 1 - j)
+	}
+
+	return res
+}
+
+// Grid is a rendering of a tag, true for its white modules.
+type Grid [][]bool
+
+func NewGrid(width int) Grid {
+	res := make(Grid, width)
+	for y := range res {
+		res[y] = make([]bool, width)
+	}
+	return res
+}
+
+// Rotate returns the grid rotated by quarters of turn clockwise.
+func (g Grid) Rotate(quarters int) Grid {
+	res := g
+	for q := 0; q < ((quarters%4)+4)%4; q++ {
+		rotated := NewGrid(len(res))
+		for y := range rotated {
+			for x := range rotated[y] {
+				rotated[y][x] = res[len(res)-1-x][y]
+			}
+		}
+		res = rotated
+	}
+	return res
+}
+
+func (g Grid) Equal(other Grid) bool {
+	if len(g) != len(other) {
+		return false
+	}
+	for y := range g {
+		if len(g[y]) != len(other[y]) {
+			return false
+		}
+		for x := range g[y] {
+			if g[y][x] != other[y][x] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g Grid) Lines() []string {
+	res := make([]string, 0, len(g))
+	for _, row := range g {
+		line := make([]byte, 0, len(row))
+		for _, white := range row {
+			if white == true {
+				line = append(line, '.')
+			} else {
+				line = append(line, '#')
+			}
+		}
+		res = append(res, string(line))
+	}
+	return res
+}
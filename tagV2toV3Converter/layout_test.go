@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type LayoutSuite struct{}
+
+var _ = Suite(&LayoutSuite{})
+
+func (s *LayoutSuite) TestNewLayoutMatchesApriltag3(c *C) {
+	testdata := []struct {
+		D    int
+		X, Y []int
+	}{
+		{
+			D: 4,
+			X: []int{1, 2, 3, 2, 4, 4, 4, 3, 4, 3, 2, 3, 1, 1, 1, 2},
+			Y: []int{1, 1, 1, 2, 1, 2, 3, 2, 4, 4, 4, 3, 4, 3, 2, 3},
+		},
+		{
+			D: 5,
+			X: []int{1, 2, 3, 4, 2, 3, 5, 5, 5, 5, 4, 4, 5, 4, 3, 2, 4, 3, 1, 1, 1, 1, 2, 2, 3},
+			Y: []int{1, 1, 1, 1, 2, 2, 1, 2, 3, 4, 2, 3, 5, 5, 5, 5, 4, 4, 5, 4, 3, 2, 4, 3, 3},
+		},
+		{
+			D: 6,
+			X: []int{
+				1, 2, 3, 4, 5, 2, 3, 4, 3,
+				6, 6, 6, 6, 6, 5, 5, 5, 4,
+				6, 5, 4, 3, 2, 5, 4, 3, 4,
+				1, 1, 1, 1, 1, 2, 2, 2, 3,
+			},
+			Y: []int{
+				1, 1, 1, 1, 1, 2, 2, 2, 3,
+				1, 2, 3, 4, 5, 2, 3, 4, 3,
+				6, 6, 6, 6, 6, 5, 5, 5, 4,
+				6, 5, 4, 3, 2, 5, 4, 3, 4,
+			},
+		},
+	}
+	for _, d := range testdata {
+		l := NewLayout(d.D, 1)
+		c.Check(l.X, DeepEquals, d.X, Commentf("d: %d", d.D))
+		c.Check(l.Y, DeepEquals, d.Y, Commentf("d: %d", d.D))
+	}
+}
+
+func (s *LayoutSuite) TestConvertsApriltag2Codes(c *C) {
+	correspondances, err := BuildCorrespondances(OldLayout(6, 1), NewLayout(6, 1))
+	c.Assert(err, IsNil)
+	// first code of tag36h11 in apriltag 2 and 3
+	c.Check(ConvertCode(correspondances, 0xd5d628584), Equals, uint64(0xd7e00984b))
+
+	_, err = BuildCorrespondances(OldLayout(6, 1), NewLayout(5, 1))
+	c.Check(err, ErrorMatches, "Layouts have 36 and 25 bits")
+	_, err = BuildCorrespondances(OldLayout(4, 1), NewLayout(4, 2))
+	c.Check(err, ErrorMatches, "Could not find correspondances for bit 0")
+}
+
+func (s *LayoutSuite) TestParsesOldFamilies(c *C) {
+	testdata := []struct {
+		Source string
+		Codes  []uint64
+		Error  string
+	}{
+		{
+			Source: `
+   tf->black_border = 1;
+   tf->d = 4;
+   tf->h = 5;
+   tf->ncodes = 2;
+   tf->codes = calloc(2, sizeof(uint64_t));
+   tf->codes[1] = 0x000000000000c4f9UL;
+   tf->codes[0] = 0x000000000000231bUL;
+`,
+			Codes: []uint64{0x231b, 0xc4f9},
+		},
+		{
+			Source: `
+static uint64_t codedata[3] = {
+   0x000000000000231bUL,
+   0x0000000000002ea5UL,
+   0x00000000000034a7UL,
+};
+   tf->black_border = 1;
+   tf->d = 4;
+   tf->h = 5;
+   tf->ncodes = 3;
+   tf->codes = codedata;
+`,
+			Codes: []uint64{0x231b, 0x2ea5, 0x34a7},
+		},
+		{
+			Source: `tf->black_border = 1; tf->d = 4; tf->h = 5; tf->ncodes = 3; tf->codes[0] = 0x231bUL;`,
+			Error:  "Expected 3 codes, but 1 parsed",
+		},
+		{
+			Source: `tf->black_border = 1; tf->d = 4; tf->h = 5; tf->codes[0] = 0x1231bUL;`,
+			Error:  "Code 0 \\(0x1231b\\) has more than 16 bits",
+		},
+		{
+			Source: `tf->black_border = 1; tf->d = 9; tf->h = 5; tf->codes[0] = 0x1UL;`,
+			Error:  "Cannot convert 9x9 tags, codes are limited to 64 bits",
+		},
+		{
+			Source: `tf->d = 4; tf->h = 5; tf->codes[0] = 0x1UL;`,
+			Error:  "No value found for 'black_border'",
+		},
+	}
+	for _, d := range testdata {
+		f, err := ParseOldFamily("16h5", d.Source)
+		if len(d.Error) > 0 {
+			c.Check(err, ErrorMatches, d.Error)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Check(f.Codes, DeepEquals, d.Codes)
+		c.Check(f.Hamming, Equals, 5)
+		c.Check(f.WidthAtBorder, Equals, 6)
+		c.Check(f.TotalWidth, Equals, 8)
+	}
+}
+
+func (s *LayoutSuite) TestConvertedFamiliesRenderTheSame(c *C) {
+	for _, d := range []int{4, 5, 6} {
+		old := &Family{
+			Name:          "test",
+			Layout:        OldLayout(d, 1),
+			Border:        1,
+			WidthAtBorder: d + 2,
+			TotalWidth:    d + 4,
+		}
+		for i := 0; i < 64; i++ {
+			old.Codes = append(old.Codes, uint64(i*0x9e3779b9)&(uint64(1)<<uint(d*d)-1))
+		}
+		converted, err := old.Convert(NewLayout(d, 1))
+		c.Assert(err, IsNil)
+		c.Check(old.Mismatches(converted, 0), HasLen, 0)
+		c.Check(old.Mismatches(old, 0), HasLen, 0)
+
+		// the identity is wrong as soon as a code is not symmetric
+		unconverted := *converted
+		unconverted.Codes = old.Codes
+		c.Check(old.Mismatches(&unconverted, 0), Not(HasLen), 0)
+	}
+}
+
+func (s *LayoutSuite) TestWritesOutputs(c *C) {
+	f := &Family{
+		Name:          "16h5",
+		Hamming:       5,
+		Codes:         []uint64{0x231b, 0xc4f9},
+		Layout:        NewLayout(4, 1),
+		Border:        1,
+		WidthAtBorder: 6,
+		TotalWidth:    8,
+	}
+	buffer := &bytes.Buffer{}
+	c.Assert(WriteJSON(buffer, f), IsNil)
+	res := jsonFamily{}
+	c.Assert(json.Unmarshal(buffer.Bytes(), &res), IsNil)
+	c.Check(res.Codes, DeepEquals, []string{"0x231b", "0xc4f9"})
+	c.Check(res.NBits, Equals, 16)
+	c.Check(res.BitX, DeepEquals, f.Layout.X)
+
+	buffer.Reset()
+	c.Assert(WriteGo(buffer, f, "families", "tag16h5.c"), IsNil)
+	c.Check(buffer.String(), Matches, "(?s).*var Tag16h5 = &TagFamily\\{.*Name: +\"16H5\",.*0x000000000000c4f9,.*")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+type Options struct {
+	Format   string `short:"f" long:"format" description:"Format of the converted family" choice:"c" choice:"go" choice:"json" default:"c"`
+	Output   string `short:"o" long:"output" description:"Directory of the outputs, defaults to the directory of the input"`
+	Package  string `long:"package" description:"Package of the Go output" default:"families"`
+	Verify   string `long:"verify" description:"Converted family, as C or JSON, to check against the input rather than converting it" value-name:"FILE"`
+	Rotation int    `long:"rotation" description:"Clockwise rotation of the converted tags from the original ones, in degrees" choice:"0" choice:"90" choice:"180" choice:"270" default:"0"`
+	Args     struct {
+		File string `positional-arg-name:"tagXXX.c" description:"C source of an apriltag 2 family"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (opts *Options) outputs(name string) []string {
+	dir := opts.Output
+	if len(dir) == 0 {
+		dir = filepath.Dir(opts.Args.File)
+	}
+	base := filepath.Join(dir, fmt.Sprintf("tag%s-converted", name))
+	if opts.Format == "c" {
+		return []string{base + ".h", base + ".c"}
+	}
+	return []string{base + "." + opts.Format}
+}
+
+func (opts *Options) write(f *Family) error {
+	paths := opts.outputs(f.Name)
+	files := make([]*os.File, 0, len(paths))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, p := range paths {
+		file, err := os.Create(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	var err error
+	switch opts.Format {
+	case "go":
+		err = WriteGo(files[0], f, opts.Package, filepath.Base(opts.Args.File))
+	case "json":
+		err = WriteJSON(files[0], f)
+	default:
+		err = WriteC(files[0], files[1], f)
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	files = nil
+	log.Printf("Wrote %s", paths)
+	return nil
+}
+
+// readOld reads the apriltag 2 family of file.
+func readOld(file string) (*Family, error) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	old, err := ParseOldFamily(FamilyName(filepath.Base(file)), string(source))
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse '%s': %s", file, err)
+	}
+	return old, nil
+}
+
+// convert converts the family of file, and checks each converted code
+// renders the same grid of modules than the original.
+func convert(file string) (*Family, error) {
+	old, err := readOld(file)
+	if err != nil {
+		return nil, err
+	}
+	d := old.WidthAtBorder - 2*old.Border
+	converted, err := old.Convert(NewLayout(d, old.Border))
+	if err != nil {
+		return nil, err
+	}
+	if mismatches := old.Mismatches(converted, 0); len(mismatches) > 0 {
+		return nil, fmt.Errorf("%d converted codes do not render as the original ones, first is %d", len(mismatches), mismatches[0])
+	}
+	log.Printf("Converted %d codes of %s (%dx%d bits, h=%d)", len(converted.Codes), converted.Name, d, d, converted.Hamming)
+	return converted, nil
+}
+
+// verify checks each code of the converted family renders as the
+// original one rotated by opts.Rotation, and reports the ones which do
+// not.
+func (opts *Options) verify() error {
+	old, err := readOld(opts.Args.File)
+	if err != nil {
+		return err
+	}
+	converted, err := ReadFamily(opts.Verify)
+	if err != nil {
+		return err
+	}
+	if old.TotalWidth != converted.TotalWidth {
+		return fmt.Errorf("Original tags are %d modules wide, but converted ones are %d", old.TotalWidth, converted.TotalWidth)
+	}
+	mismatches := old.Mismatches(converted, opts.Rotation/90)
+	if len(mismatches) > 0 {
+		WriteReport(os.Stdout, old, converted, opts.Rotation/90, mismatches, 5)
+		return fmt.Errorf("%d of %d codes of '%s' do not render as the original ones rotated by %d degrees",
+			len(mismatches), len(old.Codes), opts.Verify, opts.Rotation)
+	}
+	log.Printf("All %d codes of '%s' render as the original ones rotated by %d degrees", len(old.Codes), opts.Verify, opts.Rotation)
+	return nil
+}
+
+func Execute() error {
+	opts := &Options{}
+	if _, err := flags.Parse(opts); err != nil {
+		return err
+	}
+	if len(opts.Verify) > 0 {
+		return opts.verify()
+	}
+	converted, err := convert(opts.Args.File)
+	if err != nil {
+		return err
+	}
+	return opts.write(converted)
+}
+
+func main() {
+	if err := Execute(); err != nil {
+		log.Fatalf("Unhandled error: %s", err)
+	}
+}
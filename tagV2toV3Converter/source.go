@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Family is an apriltag family and the layout of its bits.
+type Family struct {
+	Name    string
+	Hamming int
+	Codes   []uint64
+	Layout  Layout
+	// Border is the width of the black border, in modules
+	Border        int
+	WidthAtBorder int
+	TotalWidth    int
+	// ReversedBorder is true for a white border
+	ReversedBorder bool
+}
+
+const codeLiteral = `(0[xX][0-9a-fA-F]+|[0-9]+)[uUlL]*`
+
+var arrayRx = regexp.MustCompile(`uint64_t\s+\w+\s*\[\s*[0-9]*\s*\]\s*=\s*\{([^}]*)\}`)
+
+var literalRx = regexp.MustCompile(codeLiteral)
+
+func FindMultipleField(source, field string) ([]uint64, error) {
+	rx, err := regexp.Compile(fmt.Sprintf(`tf->%s\s*=\s*%s\s*;`, field, codeLiteral))
+	if err != nil {
+		return nil, err
+	}
+	matches := rx.FindAllStringSubmatch(source, -1)
+
+	res := []uint64{}
+	for _, m := range matches {
+		r, err := strconv.ParseUint(m[1], 0, 64)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+func FindField(source, field string) (int, error) {
+	arr, err := FindMultipleField(source, field)
+	if err != nil {
+		return 0, err
+	}
+	if len(arr) > 1 {
+		return int(arr[0]), fmt.Errorf("Multiple values found for '%s'", field)
+	}
+	if len(arr) == 0 {
+		return 0, fmt.Errorf("No value found for '%s'", field)
+	}
+	return int(arr[0]), nil
+}
+
+// FindCodes returns the codes of a family, either set one by one as
+// tf->codes[i] or listed in an uint64_t array.
+func FindCodes(source string) ([]uint64, error) {
+	rx := regexp.MustCompile(fmt.Sprintf(`tf->codes\[\s*([0-9]+)\s*\]\s*=\s*%s\s*;`, codeLiteral))
+	matches := rx.FindAllStringSubmatch(source, -1)
+	if len(matches) > 0 {
+		res := make([]uint64, len(matches))
+		set := make([]bool, len(matches))
+		for _, m := range matches {
+			i, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, err
+			}
+			if i >= len(res) || set[i] == true {
+				return nil, fmt.Errorf("Codes are not indexed from 0 to %d (got codes[%d] twice or out of range)", len(res)-1, i)
+			}
+			res[i], err = strconv.ParseUint(m[2], 0, 64)
+			if err != nil {
+				return nil, err
+			}
+			set[i] = true
+		}
+		return res, nil
+	}
+
+	array := arrayRx.FindStringSubmatch(source)
+	if array == nil {
+		return nil, fmt.Errorf("No codes found")
+	}
+	res := []uint64{}
+	for _, m := range literalRx.FindAllStringSubmatch(array[1], -1) {
+		c, err := strconv.ParseUint(m[1], 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// FamilyName returns the name of a family from the name of its file,
+// such as 36h11 for tag36h11.c or tag36h11-converted.json.
+func FamilyName(file string) string {
+	name := strings.TrimSuffix(file, filepath.Ext(file))
+	return strings.TrimPrefix(strings.TrimSuffix(name, "-converted"), "tag")
+}
+
+// ParseOldFamily parses the C source of an apriltag 2 family, whose d x
+// d bits are in row major order within a black border.
+func ParseOldFamily(name, source string) (*Family, error) {
+	codes, err := FindCodes(source)
+	if err != nil {
+		return nil, err
+	}
+	h, err := FindField(source, "h")
+	if err != nil {
+		return nil, err
+	}
+	if ncodes, err := FindField(source, "ncodes"); err == nil && ncodes != len(codes) {
+		return nil, fmt.Errorf("Expected %d codes, but %d parsed", ncodes, len(codes))
+	}
+	blackBorder, err := FindField(source, "black_border")
+	if err != nil {
+		return nil, err
+	}
+	d, err := FindField(source, "d")
+	if err != nil {
+		return nil, err
+	}
+	if d < 2 || d*d > 64 {
+		return nil, fmt.Errorf("Cannot convert %dx%d tags, codes are limited to 64 bits", d, d)
+	}
+	for i, c := range codes {
+		if c>>uint(d*d) != 0 {
+			return nil, fmt.Errorf("Code %d (0x%x) has more than %d bits", i, c, d*d)
+		}
+	}
+
+	return &Family{
+		Name:          name,
+		Hamming:       h,
+		Codes:         codes,
+		Layout:        OldLayout(d, blackBorder),
+		Border:        blackBorder,
+		WidthAtBorder: d + 2*blackBorder,
+		TotalWidth:    d + 2*blackBorder + 2,
+	}, nil
+}
+
+// Convert returns the family with its codes remapped to the layout to.
+func (f *Family) Convert(to Layout) (*Family, error) {
+	correspondances, err := BuildCorrespondances(f.Layout, to)
+	if err != nil {
+		return nil, err
+	}
+	res := *f
+	res.Layout = to
+	res.Codes = make([]uint64, 0, len(f.Codes))
+	for _, c := range f.Codes {
+		res.Codes = append(res.Codes, ConvertCode(correspondances, c))
+	}
+	return &res, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FindLocations returns the values of tf->field[i], which may be
+// negative outside the border.
+func FindLocations(source, field string, n int) ([]int, error) {
+	rx, err := regexp.Compile(fmt.Sprintf(`tf->%s\[\s*([0-9]+)\s*\]\s*=\s*(-?[0-9]+)\s*;`, field))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]int, n)
+	set := make([]bool, n)
+	for _, m := range rx.FindAllStringSubmatch(source, -1) {
+		i, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+		if i >= n || set[i] == true {
+			return nil, fmt.Errorf("%s is not indexed from 0 to %d (got %s[%d] twice or out of range)", field, n-1, field, i)
+		}
+		if res[i], err = strconv.Atoi(m[2]); err != nil {
+			return nil, err
+		}
+		set[i] = true
+	}
+	for i, ok := range set {
+		if ok == false {
+			return nil, fmt.Errorf("No value found for '%s[%d]'", field, i)
+		}
+	}
+	return res, nil
+}
+
+// ParseFamily parses the C source of an apriltag 3 family, whose bits
+// are located by bit_x and bit_y.
+func ParseFamily(name, source string) (*Family, error) {
+	codes, err := FindCodes(source)
+	if err != nil {
+		return nil, err
+	}
+	res := &Family{Name: name, Codes: codes}
+	for field, value := range map[string]*int{
+		"h":               &res.Hamming,
+		"width_at_border": &res.WidthAtBorder,
+		"total_width":     &res.TotalWidth,
+	} {
+		if *value, err = FindField(source, field); err != nil {
+			return nil, err
+		}
+	}
+	nbits, err := FindField(source, "nbits")
+	if err != nil {
+		return nil, err
+	}
+	if res.Layout.X, err = FindLocations(source, "bit_x", nbits); err != nil {
+		return nil, err
+	}
+	if res.Layout.Y, err = FindLocations(source, "bit_y", nbits); err != nil {
+		return nil, err
+	}
+	res.ReversedBorder = regexp.MustCompile(`tf->reversed_border\s*=\s*(true|1)\s*;`).MatchString(source)
+	return res, res.check()
+}
+
+func ReadJSON(r io.Reader) (*Family, error) {
+	f := jsonFamily{}
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if len(f.BitX) != f.NBits || len(f.BitY) != f.NBits {
+		return nil, fmt.Errorf("Expected %d bit locations, got %d bit_x and %d bit_y", f.NBits, len(f.BitX), len(f.BitY))
+	}
+	res := &Family{
+		Name:           f.Name,
+		Hamming:        f.Hamming,
+		Layout:         Layout{X: f.BitX, Y: f.BitY},
+		WidthAtBorder:  f.WidthAtBorder,
+		TotalWidth:     f.TotalWidth,
+		ReversedBorder: f.ReversedBorder,
+	}
+	for i, s := range f.Codes {
+		c, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid code %d '%s': %s", i, s, err)
+		}
+		res.Codes = append(res.Codes, c)
+	}
+	return res, res.check()
+}
+
+// ReadFamily reads a converted family, as C or JSON.
+func ReadFamily(path string) (*Family, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var res *Family
+	name := FamilyName(filepath.Base(path))
+	switch filepath.Ext(path) {
+	case ".c":
+		res, err = ParseFamily(name, string(data))
+	case ".json":
+		res, err = ReadJSON(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("Cannot read '%s': expected a .c or .json family", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse '%s': %s", path, err)
+	}
+	return res, nil
+}
+
+// check ensures every bit lies within the tag.
+func (f *Family) check() error {
+	if f.TotalWidth < f.WidthAtBorder || f.WidthAtBorder < 0 {
+		return fmt.Errorf("Invalid widths %d at border and %d in total", f.WidthAtBorder, f.TotalWidth)
+	}
+	if f.Layout.NBits() > 64 {
+		return fmt.Errorf("Codes of %d bits do not fit in 64 bits", f.Layout.NBits())
+	}
+	offset := (f.TotalWidth - f.WidthAtBorder) / 2
+	for i, x := range f.Layout.X {
+		y := f.Layout.Y[i]
+		if x+offset < 0 || x+offset >= f.TotalWidth || y+offset < 0 || y+offset >= f.TotalWidth {
+			return fmt.Errorf("Bit %d at (%d,%d) is outside of the tag", i, x, y)
+		}
+	}
+	return nil
+}
+
+// Render renders code as it is drawn: the white outer modules, the
+// black border and the white modules of the set bits.
+func (f *Family) Render(code uint64) Grid {
+	res := NewGrid(f.TotalWidth)
+	offset := (f.TotalWidth - f.WidthAtBorder) / 2
+	for y := range res {
+		for x := range res[y] {
+			inside := x >= offset && x < offset+f.WidthAtBorder && y >= offset && y < offset+f.WidthAtBorder
+			res[y][x] = inside == f.ReversedBorder
+		}
+	}
+	nbits := f.Layout.NBits()
+	for i := 0; i < nbits; i++ {
+		res[offset+f.Layout.Y[i]][offset+f.Layout.X[i]] = code&(uint64(1)<<uint(nbits-1-i)) != 0
+	}
+	return res
+}
+
+// Mismatches returns the indexes of the codes of f which, once rotated
+// by quarters of turn clockwise, do not render as the ones of other.
+func (f *Family) Mismatches(other *Family, quarters int) []int {
+	res := []int{}
+	for i, c := range f.Codes {
+		if i >= len(other.Codes) ||
+			f.Render(c).Rotate(quarters).Equal(other.Render(other.Codes[i])) == false {
+			res = append(res, i)
+		}
+	}
+	for i := len(f.Codes); i < len(other.Codes); i++ {
+		res = append(res, i)
+	}
+	return res
+}
+
+// WriteReport lists the mismatching codes of original and converted,
+// and draws the grids of the first maxGrids ones side by side, the
+// original one rotated by quarters of turn clockwise.
+func WriteReport(w io.Writer, original, converted *Family, quarters int, mismatches []int, maxGrids int) {
+	codeString := func(f *Family, i int) string {
+		if i >= len(f.Codes) {
+			return "missing"
+		}
+		return fmt.Sprintf("0x%0*x", (f.Layout.NBits()+3)/4, f.Codes[i])
+	}
+	for n, i := range mismatches {
+		fmt.Fprintf(w, "code %d: original %s, converted %s\n", i, codeString(original, i), codeString(converted, i))
+		if n >= maxGrids || i >= len(original.Codes) || i >= len(converted.Codes) {
+			continue
+		}
+		left := original.Render(original.Codes[i]).Rotate(quarters).Lines()
+		right := converted.Render(converted.Codes[i]).Lines()
+		for l := 0; l < len(left) || l < len(right); l++ {
+			line := []string{strings.Repeat(" ", len(left[0])), ""}
+			if l < len(left) {
+				line[0] = left[l]
+			}
+			if l < len(right) {
+				line[1] = right[l]
+			}
+			fmt.Fprintf(w, "    %s    %s\n", line[0], line[1])
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type VerifySuite struct {
+	Old, Converted *Family
+}
+
+var _ = Suite(&VerifySuite{})
+
+func (s *VerifySuite) SetUpTest(c *C) {
+	s.Old = &Family{
+		Name:          "25h9",
+		Hamming:       9,
+		Codes:         []uint64{0x156f1f4, 0x1f28cd5, 0x16ce32c},
+		Layout:        OldLayout(5, 1),
+		Border:        1,
+		WidthAtBorder: 7,
+		TotalWidth:    9,
+	}
+	var err error
+	s.Converted, err = s.Old.Convert(NewLayout(5, 1))
+	c.Assert(err, IsNil)
+}
+
+// rotateCode returns the code of the tag rotated by a quarter of turn
+// clockwise, in the apriltag 3 layout of d x d bits.
+func rotateCode(code uint64, d int) uint64 {
+	nbits := uint(d * d)
+	quadrant := nbits / 4
+	res := uint64(0)
+	for i := uint(0); i < nbits; i++ {
+		if code&(uint64(1)<<(nbits-1-i)) == 0 {
+			continue
+		}
+		j := i
+		if i < 4*quadrant {
+			j = (i + quadrant) % (4 * quadrant)
+		}
+		res |= uint64(1) << (nbits - 1 - j)
+	}
+	return res
+}
+
+func (s *VerifySuite) TestRendersTags(c *C) {
+	c.Check(s.Old.Render(s.Old.Codes[0]).Lines(), DeepEquals, []string{
+		".........",
+		".#######.",
+		".#.#.#.#.",
+		".##..#.#.",
+		".#...###.",
+		".##....#.",
+		".#.#.###.",
+		".#######.",
+		".........",
+	})
+	c.Check(s.Converted.Render(s.Converted.Codes[0]).Equal(s.Old.Render(s.Old.Codes[0])), Equals, true)
+
+	reversed := *s.Old
+	reversed.ReversedBorder = true
+	c.Check(reversed.Render(0).Lines()[0], Equals, "#########")
+	c.Check(reversed.Render(0).Lines()[4], Equals, "#.#####.#")
+}
+
+func (s *VerifySuite) TestRotatesGrids(c *C) {
+	g := Grid{{true, false}, {false, false}}
+	c.Check(g.Rotate(1), DeepEquals, Grid{{false, true}, {false, false}})
+	c.Check(g.Rotate(2), DeepEquals, Grid{{false, false}, {false, true}})
+	c.Check(g.Rotate(-1), DeepEquals, Grid{{false, false}, {true, false}})
+	c.Check(g.Rotate(4), DeepEquals, g)
+}
+
+func (s *VerifySuite) TestVerifiesUpToARotation(c *C) {
+	for _, d := range []int{4, 5, 6} {
+		old := &Family{
+			Layout:        OldLayout(d, 1),
+			WidthAtBorder: d + 2,
+			TotalWidth:    d + 4,
+		}
+		for i := 1; i < 32; i++ {
+			old.Codes = append(old.Codes, uint64(i*0x9e3779b9)&(uint64(1)<<uint(d*d)-1))
+		}
+		converted, err := old.Convert(NewLayout(d, 1))
+		c.Assert(err, IsNil)
+		rotated := *converted
+		rotated.Codes = nil
+		for _, code := range converted.Codes {
+			rotated.Codes = append(rotated.Codes, rotateCode(code, d))
+		}
+		c.Check(old.Mismatches(&rotated, 1), HasLen, 0, Commentf("d: %d", d))
+		c.Check(old.Mismatches(&rotated, 0), Not(HasLen), 0, Commentf("d: %d", d))
+		c.Check(old.Mismatches(&rotated, 3), Not(HasLen), 0, Commentf("d: %d", d))
+	}
+}
+
+func (s *VerifySuite) TestReadsConvertedFamilies(c *C) {
+	header, source := &bytes.Buffer{}, &bytes.Buffer{}
+	c.Assert(WriteC(header, source, s.Converted), IsNil)
+	fromC, err := ParseFamily("25h9", source.String())
+	c.Assert(err, IsNil)
+
+	buffer := &bytes.Buffer{}
+	c.Assert(WriteJSON(buffer, s.Converted), IsNil)
+	fromJSON, err := ReadJSON(buffer)
+	c.Assert(err, IsNil)
+
+	for _, f := range []*Family{fromC, fromJSON} {
+		c.Check(f.Codes, DeepEquals, s.Converted.Codes)
+		c.Check(f.Layout, DeepEquals, s.Converted.Layout)
+		c.Check(f.Hamming, Equals, 9)
+		c.Check(f.WidthAtBorder, Equals, 7)
+		c.Check(f.TotalWidth, Equals, 9)
+		c.Check(f.ReversedBorder, Equals, false)
+		c.Check(s.Old.Mismatches(f, 0), HasLen, 0)
+	}
+
+	_, err = ParseFamily("25h9", `tf->h = 9; tf->width_at_border = 7; tf->total_width = 9; tf->nbits = 1;
+tf->codes[0] = 0x1UL; tf->bit_x[0] = 1;`)
+	c.Check(err, ErrorMatches, "No value found for 'bit_y\\[0\\]'")
+	_, err = ParseFamily("25h9", `tf->h = 9; tf->width_at_border = 7; tf->total_width = 9; tf->nbits = 1;
+tf->codes[0] = 0x1UL; tf->bit_x[0] = -2; tf->bit_y[0] = 1;`)
+	c.Check(err, ErrorMatches, "Bit 0 at \\(-2,1\\) is outside of the tag")
+}
+
+func (s *VerifySuite) TestReportsMismatches(c *C) {
+	wrong := *s.Converted
+	wrong.Codes = []uint64{s.Converted.Codes[0], s.Old.Codes[1]}
+	mismatches := s.Old.Mismatches(&wrong, 0)
+	c.Check(mismatches, DeepEquals, []int{1, 2})
+
+	buffer := &bytes.Buffer{}
+	WriteReport(buffer, s.Old, &wrong, 0, mismatches, 5)
+	lines := bytes.Split(buffer.Bytes(), []byte("\n"))
+	c.Assert(len(lines), Equals, 12)
+	c.Check(string(lines[0]), Equals, "code 1: original 0x1f28cd5, converted 0x1f28cd5")
+	c.Check(string(lines[1]), Equals, "    .........    .........")
+	c.Check(string(lines[10]), Equals, "code 2: original 0x16ce32c, converted missing")
+}
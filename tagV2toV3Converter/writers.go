@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var headerTemplate string = `/* Copyright (C) 2013-2016, The Regents of The University of Michigan.
+All rights reserved.
+This software was developed in the APRIL Robotics Lab under the
+direction of Edwin Olson, ebolson@umich.edu. This software may be
+available under alternative licensing terms; contact the address above.
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR
+ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+(INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+(INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+The views and conclusions contained in the software and documentation are those
+of the authors and should not be interpreted as representing official policies,
+either expressed or implied, of the Regents of The University of Michigan.
+*/
+
+#ifndef %s
+#define %s
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+apriltag_family_t *tag%s_create();
+void tag%s_destroy(apriltag_family_t *tf);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
+`
+
+var cBeginTemplate string = `/* Copyright (C) 2013-2016, The Regents of The University of Michigan.
+All rights reserved.
+This software was developed in the APRIL Robotics Lab under the
+direction of Edwin Olson, ebolson@umich.edu. This software may be
+available under alternative licensing terms; contact the address above.
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
+ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED
+WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR
+ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES
+(INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND
+ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+(INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF THIS
+SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+The views and conclusions contained in the software and documentation are those
+of the authors and should not be interpreted as representing official policies,
+either expressed or implied, of the Regents of The University of Michigan.
+*/
+
+#include <stdlib.h>
+#include "apriltag.h"
+
+apriltag_family_t __attribute__((optimize("O0"))) *tag%s_create()
+{
+   apriltag_family_t *tf = calloc(1, sizeof(apriltag_family_t));
+   tf->name = strdup("tag%s");
+   tf->h = %d;
+   tf->ncodes = %d;
+   tf->codes = calloc(%d, sizeof(uint64_t));
+`
+
+var cMidTemplate string = `   tf->nbits = %d;
+   tf->bit_x = calloc(%d, sizeof(uint32_t));
+   tf->bit_y = calloc(%d, sizeof(uint32_t));
+`
+
+var cEndTemplate string = `   tf->width_at_border = %d;
+   tf->total_width = %d;
+   tf->reversed_border = %s;
+   return tf;
+}
+
+void tag%s_destroy(apriltag_family_t *tf)
+{
+   free(tf->codes);
+   free(tf->bit_x);
+   free(tf->bit_y);
+   free(tf->name);
+   free(tf);
+}
+`
+
+// WriteC writes the apriltag 3 header and source of f.
+func WriteC(header, source io.Writer, f *Family) error {
+	guardName := "_TAG" + strings.ToUpper(f.Name)
+	if _, err := fmt.Fprintf(header, headerTemplate, guardName, guardName, f.Name, f.Name); err != nil {
+		return err
+	}
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, cBeginTemplate, f.Name, f.Name, f.Hamming, len(f.Codes), len(f.Codes))
+	for i, c := range f.Codes {
+		fmt.Fprintf(buffer, "   tf->codes[%d] = 0x%016xUL;\n", i, c)
+	}
+	nbits := f.Layout.NBits()
+	fmt.Fprintf(buffer, cMidTemplate, nbits, nbits, nbits)
+	for i := 0; i < nbits; i++ {
+		fmt.Fprintf(buffer, "   tf->bit_x[%d] = %d;\n   tf->bit_y[%d] = %d;\n", i, f.Layout.X[i], i, f.Layout.Y[i])
+	}
+	fmt.Fprintf(buffer, cEndTemplate, f.WidthAtBorder, f.TotalWidth, "false", f.Name)
+	_, err := io.Copy(source, buffer)
+	return err
+}
+
+// goIdentifier returns the exported name of the variable holding f.
+func goIdentifier(f *Family) string {
+	return "Tag" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, f.Name)
+}
+
+// WriteGo writes f as a families.TagFamily variable of package pkg.
+func WriteGo(w io.Writer, f *Family, pkg, source string) error {
+	qualifier := "families."
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "// Code generated by tagV2toV3Converter from %s. DO NOT EDIT.\n\npackage %s\n\n", source, pkg)
+	if pkg == "families" {
+		qualifier = ""
+	} else {
+		fmt.Fprintf(buffer, "import \"github.com/formicidae-tracker/tag-layouter/families\"\n\n")
+	}
+	nbits := f.Layout.NBits()
+	fmt.Fprintf(buffer, "// %s is the apriltag family %s with the apriltag 3 layout.\n", goIdentifier(f), f.Name)
+	fmt.Fprintf(buffer, "var %s = &%sTagFamily{\n", goIdentifier(f), qualifier)
+	fmt.Fprintf(buffer, "Name: %q,\nNBits: %d,\nHamming: %d,\nTotalWidth: %d,\nWidthAtBorder: %d,\nReversedBorder: false,\n",
+		strings.ToUpper(f.Name), nbits, f.Hamming, f.TotalWidth, f.WidthAtBorder)
+	writeGoSlice(buffer, "LocationX", "int", nbits, func(i int) string { return fmt.Sprintf("%d", f.Layout.X[i]) })
+	writeGoSlice(buffer, "LocationY", "int", nbits, func(i int) string { return fmt.Sprintf("%d", f.Layout.Y[i]) })
+	writeGoSlice(buffer, "Inside", "bool", nbits, func(i int) string {
+		return fmt.Sprintf("%t", f.Layout.X[i] < f.WidthAtBorder && f.Layout.Y[i] < f.WidthAtBorder)
+	})
+	writeGoSlice(buffer, "Codes", "uint64", len(f.Codes), func(i int) string { return fmt.Sprintf("0x%016x", f.Codes[i]) })
+	fmt.Fprintf(buffer, "}\n")
+
+	formatted, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+func writeGoSlice(w io.Writer, field, typeName string, n int, value func(i int) string) {
+	fmt.Fprintf(w, "%s: []%s{", field, typeName)
+	for i := 0; i < n; i++ {
+		if i%8 == 0 {
+			fmt.Fprintf(w, "\n")
+		} else {
+			fmt.Fprintf(w, " ")
+		}
+		fmt.Fprintf(w, "%s,", value(i))
+	}
+	fmt.Fprintf(w, "\n},\n")
+}
+
+// jsonFamily is the JSON representation of a family, with its codes
+// as hexadecimal strings.
+type jsonFamily struct {
+	Name           string   `json:"name"`
+	Hamming        int      `json:"hamming"`
+	NBits          int      `json:"nbits"`
+	TotalWidth     int      `json:"total_width"`
+	WidthAtBorder  int      `json:"width_at_border"`
+	ReversedBorder bool     `json:"reversed_border"`
+	BitX           []int    `json:"bit_x"`
+	BitY           []int    `json:"bit_y"`
+	Codes          []string `json:"codes"`
+}
+
+func WriteJSON(w io.Writer, f *Family) error {
+	res := jsonFamily{
+		Name:          f.Name,
+		Hamming:       f.Hamming,
+		NBits:         f.Layout.NBits(),
+		TotalWidth:    f.TotalWidth,
+		WidthAtBorder: f.WidthAtBorder,
+		BitX:          f.Layout.X,
+		BitY:          f.Layout.Y,
+		Codes:         make([]string, 0, len(f.Codes)),
+	}
+	for _, c := range f.Codes {
+		res.Codes = append(res.Codes, fmt.Sprintf("0x%0*x", (res.NBits+3)/4, c))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/formicidae-tracker/tag-layouter/drawing"
+	"github.com/formicidae-tracker/tag-layouter/families"
+	"github.com/formicidae-tracker/tag-layouter/ranges"
+)
+
+type TagCommand struct {
+	Family      string `short:"t" long:"family" description:"Family of the tags" required:"true"`
+	IDs         string `long:"ids" description:"IDs to render, as a range expression, see README" default:"0"`
+	Module      string `short:"m" long:"module" description:"Size of a module, in pixels as '8' or '8px', or in mm as '0.2mm'" default:"8px"`
+	DPI         int    `short:"d" long:"dpi" description:"DPI of the outputs, used to convert mm" default:"300"`
+	QuietZone   int    `long:"quiet-zone" description:"Number of white modules around the tag" default:"0"`
+	Caption     bool   `long:"caption" description:"Prints the ID below the tag"`
+	Transparent bool   `long:"transparent" description:"Leaves the quiet zone and the caption background transparent"`
+	File        string `short:"f" long:"file" description:"Output files, where a verb such as %04d is replaced by the ID, defaults to '<family>_%04d.png'"`
+}
+
+// extractModuleSize parses a module size in pixels, or in mm converted
+// at DPI.
+func extractModuleSize(s string, DPI int) (int, error) {
+	value, unit := strings.TrimSpace(s), "px"
+	for _, u := range []string{"px", "mm"} {
+		if strings.HasSuffix(value, u) == true {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, u)), u
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid module size '%s': expected '<pixels>px' or '<size>mm'", s)
+	}
+	res := int(v)
+	if unit == "mm" {
+		res = int(v*float64(DPI)/25.4 + 0.5)
+	} else if float64(res) != v {
+		return 0, fmt.Errorf("Invalid module size '%s': pixels must be whole", s)
+	}
+	if res <= 0 {
+		return 0, fmt.Errorf("Module size '%s' is less than a pixel at %d DPI", s, DPI)
+	}
+	return res, nil
+}
+
+// dotsToMM converts a size in dots to the mm a drawer converts back to
+// the same number of dots.
+func dotsToMM(dots, DPI int) float64 {
+	return (float64(dots) + 0.5) * 25.4 / float64(DPI)
+}
+
+// newFileDrawer returns a drawer of a single page for path. Vector
+// pages are transparent until drawn on. Raster pages are transparent
+// RGBA if transparent is set, and otherwise grayscale starting black,
+// so the whole page must be painted.
+func newFileDrawer(path string, width, height float64, DPI int, transparent bool) (drawing.Drawer, error) {
+	if newVectorDrawer, ok := vectorDrawers[filepath.Ext(path)]; ok == true {
+		return newVectorDrawer(path, width, height, DPI)
+	}
+	opts := drawing.ImageOptions{Mode: drawing.GrayMode, Jobs: 1}
+	if transparent == true {
+		opts.Mode = drawing.RGBAMode
+	}
+	return drawing.NewImageDrawer(path, width, height, DPI, opts)
+}
+
+// integerVerb matches a Printf verb formatting an integer.
+var integerVerb = regexp.MustCompile(`^%[-+# 0]*[0-9]*[bdoxX]`)
+
+// pathVerbs returns the number of verbs of the output pattern, which
+// has at most one, formatting an integer. %% is a literal %.
+func pathVerbs(pattern string) (int, error) {
+	res := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			continue
+		}
+		if strings.HasPrefix(pattern[i:], "%%") == true {
+			i++
+			continue
+		}
+		verb := integerVerb.FindString(pattern[i:])
+		if len(verb) == 0 {
+			return 0, fmt.Errorf("Output '%s' may only have a verb formatting an integer, such as %%04d", pattern)
+		}
+		res++
+		i += len(verb) - 1
+	}
+	if res > 1 {
+		return 0, fmt.Errorf("Output '%s' has %d verbs, expected at most one", pattern, res)
+	}
+	return res, nil
+}
+
+// numberedPath returns pattern with its verb replaced by n, if any. The
+// pattern must be accepted by pathVerbs.
+func numberedPath(pattern string, n int) string {
+	if verbs, _ := pathVerbs(pattern); verbs == 0 {
+		return strings.Replace(pattern, "%%", "%", -1)
+	}
+	return fmt.Sprintf(pattern, n)
+}
+
+func (c *TagCommand) Execute(args []string) error {
+	tf, err := families.GetFamily(c.Family)
+	if err != nil {
+		return err
+	}
+	expr, err := ranges.ParseRangeExpression(c.IDs)
+	if err != nil {
+		return err
+	}
+	ids, err := expr.Select(tf)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("No tag of %s is selected by '%s'", c.Family, c.IDs)
+	}
+	module, err := extractModuleSize(c.Module, c.DPI)
+	if err != nil {
+		return err
+	}
+	pattern := c.File
+	if len(pattern) == 0 {
+		pattern = strings.ToLower(c.Family) + "_%04d.png"
+	}
+	verbs, err := pathVerbs(pattern)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 1 && verbs == 0 {
+		return fmt.Errorf("Output '%s' needs a verb such as %%04d for the IDs of %d tags", pattern, len(ids))
+	}
+
+	image := drawing.TagImage{
+		Module:      module,
+		QuietZone:   c.QuietZone,
+		Caption:     c.Caption,
+		Transparent: c.Transparent,
+	}
+	width, height := image.Size(tf)
+	for _, id := range ids {
+		path := numberedPath(pattern, id)
+		drawer, err := newFileDrawer(path, dotsToMM(width, c.DPI), dotsToMM(height, c.DPI), c.DPI, c.Transparent)
+		if err != nil {
+			return err
+		}
+		if err := image.Draw(drawer, tf, id); err != nil {
+			drawer.Close()
+			return err
+		}
+		if err := drawer.Close(); err != nil {
+			return err
+		}
+	}
+	log.Printf("%d tags of %s written as %dx%d pixels images, %d pixels per module", len(ids), tf.Name, width, height, module)
+	return nil
+}
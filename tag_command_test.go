@@ -0,0 +1,35 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type TagCommandSuite struct{}
+
+var _ = Suite(&TagCommandSuite{})
+
+func (s *TagCommandSuite) TestNumberedPaths(c *C) {
+	testdata := []struct {
+		Pattern, Path string
+		Verbs         int
+	}{
+		{"tag.png", "tag.png", 0},
+		{"100%%.png", "100%.png", 0},
+		{"tag_%04d.png", "tag_0042.png", 1},
+		{"tag_%x.png", "tag_2a.png", 1},
+		{"100%%_%-3d.png", "100%_42 .png", 1},
+	}
+	for _, d := range testdata {
+		verbs, err := pathVerbs(d.Pattern)
+		c.Check(err, IsNil, Commentf("pattern: %s", d.Pattern))
+		c.Check(verbs, Equals, d.Verbs, Commentf("pattern: %s", d.Pattern))
+		c.Check(numberedPath(d.Pattern, 42), Equals, d.Path)
+	}
+
+	for _, pattern := range []string{"tag_%s.png", "tag_%.png", "tag_%", "%v.png", "tag_%[1]d.png"} {
+		_, err := pathVerbs(pattern)
+		c.Check(err, ErrorMatches, "Output '.*' may only have a verb formatting an integer, such as %04d", Commentf("pattern: %s", pattern))
+	}
+	_, err := pathVerbs("%02d/tag_%04d.png")
+	c.Check(err, ErrorMatches, "Output '%02d/tag_%04d.png' has 2 verbs, expected at most one")
+}